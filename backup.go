@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// ------------------ BACKUP ------------------
+
+// Backups are consistent hot copies written from a read transaction, so
+// they can be taken while the TUI or daemon is running. They go to
+// <db>.backups/ unless a path is given: grb-<time>.db for 'grb backup',
+// auto-<time>.db for the daemon's rotating snapshots and
+// pre-restore-<time>.db for the copy 'grb restore' keeps of the DB it
+// replaces. Only auto-* files are rotated.
+
+const backupTimeFormat = "20060102-150405"
+
+func backupDir() string {
+	return getDBPath() + ".backups"
+}
+
+// writeBackup copies the open DB to path.
+func writeBackup(path string) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return 0, err
+	}
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	err = db.View(func(tx *bbolt.Tx) error {
+		var err error
+		size, err = tx.WriteTo(f)
+		return err
+	})
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return size, os.Rename(tmp, path)
+}
+
+// backupDB writes a backup to path, or to a new file in backupDir.
+func backupDB(path string) error {
+	if path == "" {
+		path = filepath.Join(backupDir(), "grb-"+time.Now().Format(backupTimeFormat)+".db")
+	}
+	path = expandHome(path)
+	size, err := writeBackup(path)
+	if err != nil {
+		return err
+	}
+	say(theme.success, "💾 Backed up to %s (%s)", path, formatSize(size))
+	fmt.Println("💡 Tip: Restore it with 'grb restore <file>'")
+	return nil
+}
+
+// takeSnapshot writes a daemon snapshot if the newest one is older than
+// the snapshot interval, then drops the oldest beyond snapshot_keep. It
+// returns the file written, if any.
+func takeSnapshot() (string, error) {
+	every := snapshotEvery()
+	if every <= 0 {
+		return "", nil
+	}
+	snaps := listBackups("auto-")
+	if len(snaps) > 0 && time.Since(snaps[len(snaps)-1].at) < every {
+		return "", nil
+	}
+	path := filepath.Join(backupDir(), "auto-"+time.Now().Format(backupTimeFormat)+".db")
+	if _, err := writeBackup(path); err != nil {
+		return "", err
+	}
+	snaps = listBackups("auto-")
+	for len(snaps) > snapshotKeep() {
+		os.Remove(snaps[0].path)
+		snaps = snaps[1:]
+	}
+	return path, nil
+}
+
+// removeSnapshots deletes the daemon's snapshots, which 'grb encrypt'
+// would otherwise leave in plaintext, and returns how many it removed.
+func removeSnapshots() (int, error) {
+	snaps := listBackups("auto-")
+	for i, f := range snaps {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return i, err
+		}
+	}
+	return len(snaps), nil
+}
+
+type backupFile struct {
+	path string
+	at   time.Time
+	size int64
+}
+
+// listBackups returns the backups in backupDir whose name starts with
+// prefix, oldest first.
+func listBackups(prefix string) []backupFile {
+	entries, _ := os.ReadDir(backupDir())
+	var files []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".db") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, backupFile{filepath.Join(backupDir(), name), info.ModTime(), info.Size()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].at.Before(files[j].at) })
+	return files
+}
+
+// printBackups lists every backup in backupDir, newest first.
+func printBackups() error {
+	files := listBackups("")
+	accent := theme.accent.SprintFunc()
+	label := theme.label.SprintFunc()
+
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Printf("%s %s\n", accent("💾 Backups in"), backupDir())
+	fmt.Println("─────────────────────────────────────────────")
+	if len(files) == 0 {
+		say(theme.highlight, "⚠ No backups yet.")
+		fmt.Println("💡 Tip: Run 'grb backup', or keep 'grb daemon' running for daily snapshots")
+		return nil
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		kind := "manual"
+		switch name := filepath.Base(f.path); {
+		case strings.HasPrefix(name, "auto-"):
+			kind = "snapshot"
+		case strings.HasPrefix(name, "pre-restore-"):
+			kind = "pre-restore"
+		}
+		fmt.Printf("%s  %s  %s  %s\n", f.at.Format("2006-01-02 15:04"), padRight(label(kind), 12),
+			padRight(formatSize(f.size), 8), filepath.Base(f.path))
+	}
+	fmt.Println("💡 Tip: Restore one with 'grb restore <file>'")
+	return nil
+}
+
+// ------------------ RESTORE ------------------
+
+// checkBackup opens file read-only and makes sure it is a grb database:
+// bbolt's consistency check passes and every vault's records decode. It
+// returns the number of snippets.
+func checkBackup(file string) (int, error) {
+	bdb, err := bbolt.Open(file, 0600, &bbolt.Options{ReadOnly: true, Timeout: lockTimeout})
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a grb database: %w", file, err)
+	}
+	defer bdb.Close()
+
+	count := 0
+	err = bdb.View(func(tx *bbolt.Tx) error {
+		// Drain the channel: Check keeps reading the tx until it is done.
+		var checkErr error
+		for err := range tx.Check() {
+			if checkErr == nil {
+				checkErr = err
+			}
+		}
+		if checkErr != nil {
+			return checkErr
+		}
+		if tx.Bucket(snippetsKey(defaultVault)) == nil {
+			return errors.New("no snippets bucket")
+		}
+		for _, name := range listVaults(tx) {
+			err := tx.Bucket(snippetsKey(name)).ForEach(func(k, v []byte) error {
+				if len(splitRecord(v)) < 2 {
+					return fmt.Errorf("malformed record %s in vault %s", k, name)
+				}
+				count++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a valid grb database: %w", file, err)
+	}
+	return count, nil
+}
+
+// restoreDB replaces the DB with file after validating it. The current DB
+// is kept as a pre-restore backup first.
+func restoreDB(file string, yes bool) error {
+	file = expandHome(file)
+	count, err := checkBackup(file)
+	if err != nil {
+		return err
+	}
+
+	current := 0
+	db.View(func(tx *bbolt.Tx) error {
+		for _, name := range listVaults(tx) {
+			current += tx.Bucket(snippetsKey(name)).Stats().KeyN
+		}
+		return nil
+	})
+	if !yes {
+		fmt.Fprintf(os.Stderr, "%s Replace the database (%d snippets) with %s (%d snippets)? [y/N] ",
+			theme.highlight.Sprint("⚠"), current, filepath.Base(file), count)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("aborted")
+		}
+	}
+
+	keep := filepath.Join(backupDir(), "pre-restore-"+time.Now().Format(backupTimeFormat)+".db")
+	if _, err := writeBackup(keep); err != nil {
+		return fmt.Errorf("couldn't keep a copy of the current database: %w", err)
+	}
+
+	// Copy next to the DB first so the swap is a rename. We hold the DB
+	// lock until then, so no other grb writes in between.
+	path := getDBPath()
+	tmp := path + ".restore"
+	if err := copyFile(file, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	releaseDB()
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := initDB(); err != nil {
+		return err
+	}
+
+	say(theme.success, "♻ Restored %d snippet(s) from %s", count, file)
+	fmt.Printf("💡 Tip: The previous database was saved as %s\n", keep)
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -21,6 +21,10 @@ go get github.com/charmbracelet/bubbletea@latest
 go get github.com/charmbracelet/bubbles@latest
 go get github.com/sahilm/fuzzy@latest
 go get github.com/olekukonko/tablewriter
+go get github.com/BurntSushi/toml@latest
+go get gopkg.in/yaml.v3@latest
+go get golang.org/x/crypto@latest
+go get golang.org/x/term@latest
 
 REM Step 3: Tidy modules
 echo Tidying modules...
@@ -0,0 +1,530 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ------------------ CONFIG ------------------
+
+// settings are the values that can be set at the top of config.toml, in a
+// [profiles.<name>] table, through GRB_* variables or with flags.
+type settings struct {
+	Theme        string `toml:"theme,omitempty"`         // dark, light, high-contrast, no-color
+	DB           string `toml:"db,omitempty"`            // database file
+	Editor       string `toml:"editor,omitempty"`        // command used by edit/save --editor
+	PollInterval string `toml:"poll_interval,omitempty"` // daemon clipboard check, e.g. "1s"
+	AutoCopy     *bool  `toml:"auto_copy,omitempty"`     // copy snippets on save
+	ClearAfter   string `toml:"clear_after,omitempty"`   // clear copied secrets, e.g. "30s"; "0" keeps them
+	Snapshot     string `toml:"snapshot,omitempty"`      // daemon snapshot interval, e.g. "24h"; "0" disables
+	SnapshotKeep *int   `toml:"snapshot_keep,omitempty"` // daemon snapshots kept
+	ReviewAfter  string `toml:"review_after,omitempty"`  // unused period before 'grb review' offers a snippet, e.g. "90d"
+}
+
+// config mirrors config.toml. Every field is optional.
+type config struct {
+	settings
+	Profile  string              `toml:"profile,omitempty"` // profile used without --profile
+	Keys     map[string][]string `toml:"keys"`              // TUI action -> keys, see defaultKeys
+	Profiles map[string]settings `toml:"profiles"`
+}
+
+// settingKeys lists the settings in the order 'grb config list' shows them.
+var settingKeys = []string{"theme", "db", "editor", "poll_interval", "auto_copy", "clear_after", "snapshot", "snapshot_keep", "review_after"}
+
+var (
+	cfg            config   // config.toml as written
+	active         settings // cfg merged with the profile, environment and flags
+	profile        string   // name of the active profile, if any
+	settingSources = map[string]string{}
+)
+
+// configPath returns config.toml under $XDG_CONFIG_HOME/grb (~/.config/grb
+// by default) or %APPDATA%\grb on Windows.
+func configPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "grb", "config.toml")
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "grb", "config.toml")
+}
+
+// loadConfig reads config.toml. A missing file is not an error.
+func loadConfig() (config, error) {
+	var c config
+	_, err := toml.DecodeFile(configPath(), &c)
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", configPath(), err)
+	}
+	layers := []settings{c.settings}
+	for _, p := range c.Profiles {
+		layers = append(layers, p)
+	}
+	for _, layer := range layers {
+		for _, k := range settingKeys {
+			if v, ok := layer.get(k); ok {
+				if err := (&settings{}).set(k, v); err != nil {
+					return c, fmt.Errorf("%s: %w", configPath(), err)
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+// defaultSettings are used for anything the user has not set.
+func defaultSettings() settings {
+	editor := os.Getenv("EDITOR")
+	if runtime.GOOS == "windows" {
+		editor = "notepad"
+	} else if editor == "" {
+		editor = "nano"
+	}
+	autoCopy := true
+	keep := 7
+	return settings{
+		Theme:        "dark",
+		DB:           defaultDBPath(),
+		Editor:       editor,
+		PollInterval: "1s",
+		AutoCopy:     &autoCopy,
+		ClearAfter:   "30s",
+		Snapshot:     "24h",
+		SnapshotKeep: &keep,
+		ReviewAfter:  "90d",
+	}
+}
+
+// get returns the value of key and whether it is set.
+func (s settings) get(key string) (string, bool) {
+	switch key {
+	case "theme":
+		return s.Theme, s.Theme != ""
+	case "db":
+		return s.DB, s.DB != ""
+	case "editor":
+		return s.Editor, s.Editor != ""
+	case "poll_interval":
+		return s.PollInterval, s.PollInterval != ""
+	case "auto_copy":
+		if s.AutoCopy == nil {
+			return "", false
+		}
+		return strconv.FormatBool(*s.AutoCopy), true
+	case "clear_after":
+		return s.ClearAfter, s.ClearAfter != ""
+	case "snapshot":
+		return s.Snapshot, s.Snapshot != ""
+	case "snapshot_keep":
+		if s.SnapshotKeep == nil {
+			return "", false
+		}
+		return strconv.Itoa(*s.SnapshotKeep), true
+	case "review_after":
+		return s.ReviewAfter, s.ReviewAfter != ""
+	}
+	return "", false
+}
+
+// set validates value and stores it under key.
+func (s *settings) set(key, value string) error {
+	switch key {
+	case "theme":
+		if _, ok := themes[value]; !ok {
+			return usagef("unknown theme %q (use %s)", value, strings.Join(themeNames(), ", "))
+		}
+		s.Theme = value
+	case "db":
+		if value == "" {
+			return usagef("db must not be empty")
+		}
+		s.DB = value
+	case "editor":
+		s.Editor = value
+	case "poll_interval":
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return usagef("poll_interval %q is not a positive duration like 1s or 500ms", value)
+		}
+		s.PollInterval = value
+	case "auto_copy":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return usagef("auto_copy %q is not true or false", value)
+		}
+		s.AutoCopy = &b
+	case "clear_after":
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return usagef("clear_after %q is not a duration like 30s, or 0 to never clear", value)
+		}
+		s.ClearAfter = value
+	case "snapshot":
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return usagef("snapshot %q is not a duration like 24h, or 0 to disable snapshots", value)
+		}
+		s.Snapshot = value
+	case "snapshot_keep":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return usagef("snapshot_keep %q is not a number of at least 1", value)
+		}
+		s.SnapshotKeep = &n
+	case "review_after":
+		if _, err := parseSince(value); err != nil {
+			return usagef("review_after %q is not a period like 90d, 12w or 720h", value)
+		}
+		s.ReviewAfter = value
+	default:
+		return usagef("unknown setting %q (use %s)", key, strings.Join(settingKeys, ", "))
+	}
+	return nil
+}
+
+func themeNames() []string {
+	var names []string
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveSettings fills active from, lowest first: the defaults,
+// config.toml, the selected profile, GRB_* variables and the --db flag.
+// The profile is picked by --profile, then GRB_PROFILE, then "profile".
+func resolveSettings(profileFlag, dbFlag string) error {
+	profile = firstNonEmpty(profileFlag, os.Getenv("GRB_PROFILE"), cfg.Profile)
+
+	type layer struct {
+		source string
+		s      settings
+	}
+	layers := []layer{{"default", defaultSettings()}, {"config", cfg.settings}}
+	if profile != "" {
+		p, ok := cfg.Profiles[profile]
+		if !ok {
+			return usagef("unknown profile %q in %s", profile, configPath())
+		}
+		layers = append(layers, layer{"profile " + profile, p})
+	}
+	var env settings
+	for _, k := range settingKeys {
+		name := "GRB_" + strings.ToUpper(k)
+		if v := os.Getenv(name); v != "" {
+			if err := env.set(k, v); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	layers = append(layers, layer{"env", env}, layer{"flag", settings{DB: dbFlag}})
+
+	active = settings{}
+	for _, l := range layers {
+		for _, k := range settingKeys {
+			if v, ok := l.s.get(k); ok {
+				active.set(k, v)
+				settingSources[k] = l.source
+			}
+		}
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// expandHome replaces a leading ~ with the home directory.
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[1:])
+	}
+	return path
+}
+
+// pollInterval is how often the daemon checks the clipboard.
+func pollInterval() time.Duration {
+	if d, err := time.ParseDuration(active.PollInterval); err == nil && d > 0 {
+		return d
+	}
+	return 1 * time.Second
+}
+
+// clearAfter is how long a copied secret stays on the clipboard; 0 means
+// it is never cleared.
+func clearAfter() time.Duration {
+	d, err := time.ParseDuration(active.ClearAfter)
+	if err != nil || d < 0 {
+		return 30 * time.Second
+	}
+	return d
+}
+
+// snapshotEvery is how often the daemon snapshots the DB; 0 disables it.
+func snapshotEvery() time.Duration {
+	d, err := time.ParseDuration(active.Snapshot)
+	if err != nil || d < 0 {
+		return 24 * time.Hour
+	}
+	return d
+}
+
+// snapshotKeep is how many daemon snapshots are kept.
+func snapshotKeep() int {
+	if active.SnapshotKeep == nil || *active.SnapshotKeep < 1 {
+		return 7
+	}
+	return *active.SnapshotKeep
+}
+
+// reviewAfter is how long a snippet goes unused before 'grb review' offers
+// it.
+func reviewAfter() time.Duration {
+	d, err := parseSince(active.ReviewAfter)
+	if err != nil {
+		return 90 * 24 * time.Hour
+	}
+	return d
+}
+
+// autoCopy reports whether saved snippets are copied to the clipboard.
+func autoCopy() bool {
+	return active.AutoCopy == nil || *active.AutoCopy
+}
+
+// ------------------ CONFIG COMMANDS ------------------
+
+// configGet prints the effective value of key.
+func configGet(key string) error {
+	if key == "profile" {
+		fmt.Println(profile)
+		return nil
+	}
+	v, ok := active.get(key)
+	if !ok {
+		return usagef("unknown setting %q (use profile, %s)", key, strings.Join(settingKeys, ", "))
+	}
+	fmt.Println(v)
+	return nil
+}
+
+// configList prints every effective setting and where it came from.
+func configList() {
+	accent := theme.accent.SprintFunc()
+	label := theme.label.SprintFunc()
+
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Printf("%s %s\n", accent("⚙ Config"), configPath())
+	fmt.Println("─────────────────────────────────────────────")
+	for _, k := range settingKeys {
+		v, _ := active.get(k)
+		fmt.Printf("%-14s = %s  %s\n", k, v, label("("+settingSources[k]+")"))
+	}
+
+	var names []string
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		fmt.Println()
+		fmt.Println(accent("Profiles"))
+		for _, name := range names {
+			mark := "  "
+			if name == profile {
+				mark = "▶ "
+			}
+			fmt.Println(mark + name)
+		}
+	}
+}
+
+// configSet writes key = value to config.toml, inside [profiles.<name>]
+// when inProfile is set. Only that line changes; other keys, comments and
+// their order are kept.
+func configSet(key, value, inProfile string) error {
+	var typed interface{} = value
+	if key == "profile" {
+		if inProfile != "" {
+			return usagef("profile can only be set at the top level")
+		}
+		if _, ok := cfg.Profiles[value]; !ok && value != "" {
+			return usagef("unknown profile %q", value)
+		}
+	} else {
+		var s settings
+		if err := s.set(key, value); err != nil {
+			return err
+		}
+		switch key {
+		case "auto_copy":
+			typed = *s.AutoCopy
+		case "snapshot_keep":
+			typed = *s.SnapshotKeep
+		}
+	}
+
+	data, err := os.ReadFile(configPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := toml.Decode(string(data), &map[string]interface{}{}); err != nil {
+		return fmt.Errorf("%s: %w", configPath(), err)
+	}
+	var line strings.Builder
+	if err := toml.NewEncoder(&line).Encode(map[string]interface{}{key: typed}); err != nil {
+		return err
+	}
+	edited := setConfigLine(string(data), inProfile, key, strings.TrimSpace(line.String()))
+	if _, err := toml.Decode(edited, &map[string]interface{}{}); err != nil {
+		return fmt.Errorf("couldn't set %s in %s (edit it with 'grb config edit'): %w", key, configPath(), err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configPath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(configPath(), []byte(edited), 0644)
+}
+
+// setConfigLine puts line, "key = value", into the TOML text: in place of
+// the key's line in the table (the top level, or [profiles.<profile>]),
+// else at the end of the table, which is added when missing. Everything
+// else, comments included, stays as it was.
+func setConfigLine(text, profile, key, line string) string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		lines = nil
+	}
+	header := regexp.MustCompile(`^\s*\[\s*profiles\s*\.\s*"?` + regexp.QuoteMeta(profile) + `"?\s*\]\s*(#.*)?$`)
+	keyRe := regexp.MustCompile(`^(\s*)"?` + regexp.QuoteMeta(key) + `"?\s*=`)
+
+	// The table runs from its header to the next one.
+	start, end := 0, len(lines)
+	if profile != "" {
+		start = -1
+		for i, l := range lines {
+			if header.MatchString(l) {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+				lines = append(lines, "")
+			}
+			name := profile
+			if !bareKeyRe.MatchString(name) {
+				name = strconv.Quote(name)
+			}
+			lines = append(lines, "[profiles."+name+"]", line)
+			return strings.Join(lines, "\n") + "\n"
+		}
+	}
+	for i := start; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(lines[i]), "[") {
+			end = i
+			break
+		}
+	}
+
+	for i := start; i < end; i++ {
+		if m := keyRe.FindStringSubmatch(lines[i]); m != nil {
+			lines[i] = m[1] + line + trailingComment(lines[i])
+			return strings.Join(lines, "\n") + "\n"
+		}
+	}
+	// After the table's last setting, before the comments and blank lines
+	// ahead of the next header.
+	at := end
+	for end < len(lines) && at > start && strings.HasPrefix(strings.TrimSpace(lines[at-1]), "#") {
+		at--
+	}
+	for at > start && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+	lines = append(lines[:at], append([]string{line}, lines[at:]...)...)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+var bareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// trailingComment returns the " # ..." after the value of a key = value
+// line: the first '#' before which the line is valid TOML.
+func trailingComment(line string) string {
+	for i := strings.IndexByte(line, '#'); i >= 0; {
+		if _, err := toml.Decode(line[:i], &map[string]interface{}{}); err == nil {
+			return " " + line[i:]
+		}
+		next := strings.IndexByte(line[i+1:], '#')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return ""
+}
+
+// configTemplate is written by 'grb config edit' when there is no file yet.
+const configTemplate = `# grb configuration. Every setting is optional.
+# GRB_<SETTING> environment variables and --db/--profile override it.
+
+# theme = "dark"          # dark, light, high-contrast, no-color
+# db = "~/.grb/grb.db"
+# editor = "nano"
+# poll_interval = "1s"    # how often the daemon checks the clipboard
+# auto_copy = true        # copy snippets on save
+# clear_after = "30s"     # clear copied secret snippets, "0" to keep them
+# snapshot = "24h"        # how often the daemon snapshots the DB, "0" to disable
+# snapshot_keep = 7       # daemon snapshots kept
+# review_after = "90d"    # unused period before 'grb review' offers a snippet
+# profile = "work"        # profile used without --profile
+
+# [profiles.work]
+# db = "~/work/grb.db"
+# auto_copy = false
+
+# [keys]
+# delete = ["d"]
+`
+
+// configEdit opens config.toml in the editor and checks it afterwards.
+func configEdit() error {
+	path := configPath()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(configTemplate), 0644); err != nil {
+			return err
+		}
+	}
+	if err := runEditor(path); err != nil {
+		return fmt.Errorf("editor: %w", err)
+	}
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	say(theme.success, "✅ Config saved")
+	return nil
+}
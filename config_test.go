@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetConfigLine(t *testing.T) {
+	const file = `# grb settings
+theme = "dark"   # or light
+editor = "vim"
+
+# Work machine
+[profiles.work]
+db = "~/work/grb.db" # synced
+
+[keys]
+delete = ["d"]
+`
+	tests := []struct {
+		name    string
+		text    string
+		profile string
+		line    string
+		want    string
+	}{
+		{"replace keeps the comment", file, "", `theme = "light"`, `# grb settings
+theme = "light" # or light
+editor = "vim"
+
+# Work machine
+[profiles.work]
+db = "~/work/grb.db" # synced
+
+[keys]
+delete = ["d"]
+`},
+		{"add at the top level", file, "", `auto_copy = false`, `# grb settings
+theme = "dark"   # or light
+editor = "vim"
+auto_copy = false
+
+# Work machine
+[profiles.work]
+db = "~/work/grb.db" # synced
+
+[keys]
+delete = ["d"]
+`},
+		{"replace in a profile", file, "work", `db = "~/w.db"`, `# grb settings
+theme = "dark"   # or light
+editor = "vim"
+
+# Work machine
+[profiles.work]
+db = "~/w.db" # synced
+
+[keys]
+delete = ["d"]
+`},
+		{"add to a profile", file, "work", `editor = "nano"`, `# grb settings
+theme = "dark"   # or light
+editor = "vim"
+
+# Work machine
+[profiles.work]
+db = "~/work/grb.db" # synced
+editor = "nano"
+
+[keys]
+delete = ["d"]
+`},
+		{"add a profile", "theme = \"dark\"\n", "my laptop", `editor = "nano"`, "theme = \"dark\"\n\n[profiles.\"my laptop\"]\neditor = \"nano\"\n"},
+		{"empty file", "", "", `theme = "light"`, "theme = \"light\"\n"},
+		{"hash inside the value", "editor = \"a#b\" # c\n", "", `editor = "x"`, "editor = \"x\" # c\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _, _ := strings.Cut(tt.line, " =")
+			if got := setConfigLine(tt.text, tt.profile, key, tt.line); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// ------------------ CLOCKS ------------------
+
+// Every snippet has a UUID that survives moves and syncs, and a
+// last-writer-wins clock per field. encode stamps the fields that changed
+// since the record was read, so no write path has to remember to. Deleted
+// snippets leave a tombstone in the "tombstones" bucket; a delete wins
+// over any edit.
+
+// clockFields are the stored fields with a clock of their own. The vault
+// also has a clock, set on create and move.
+var clockFields = []string{"text", "tag", "alias", "pinned", "secret", "archived"}
+
+// stamp orders writes: newer time wins, ties go to the larger node id so
+// every machine picks the same value.
+type stamp struct {
+	at   int64 // unix nanoseconds
+	node string
+}
+
+func (a stamp) after(b stamp) bool {
+	return a.at > b.at || a.at == b.at && a.node > b.node
+}
+
+func (a stamp) String() string {
+	return fmt.Sprintf("%d@%s", a.at, a.node)
+}
+
+func parseStamp(v string) stamp {
+	at, node, _ := strings.Cut(v, "@")
+	n, _ := strconv.ParseInt(at, 10, 64)
+	return stamp{n, node}
+}
+
+// nodeID names this database in clocks. It is created by initDB.
+var nodeID string
+
+// ownNodes are nodeID and the ids this database had before it was copied
+// or moved; their writes are still its own to export.
+var ownNodes = map[string]bool{}
+
+func newStamp() stamp {
+	return stamp{time.Now().UnixNano(), nodeID}
+}
+
+// loadNodeID reads the node id from the "meta" bucket. The id is kept
+// with the DB path and host it was made for, so a database that is copied
+// or restored elsewhere gets a new id rather than sharing clocks with the
+// original. Schema: "node" -> id|path|host|former ids (comma-separated).
+func loadNodeID(tx *bbolt.Tx) error {
+	meta, err := tx.CreateBucketIfNotExists([]byte("meta"))
+	if err != nil {
+		return err
+	}
+	host, _ := os.Hostname()
+	path, err := filepath.Abs(getDBPath())
+	if err != nil {
+		return err
+	}
+	var former []string
+	if v := meta.Get([]byte("node")); v != nil {
+		fields := splitRecord(v)
+		for len(fields) < 4 {
+			fields = append(fields, "")
+		}
+		if fields[3] != "" {
+			former = strings.Split(fields[3], ",")
+		}
+		// Ids stored before the path and host were are adopted as is.
+		if fields[1] == "" || fields[1] == path && fields[2] == host {
+			setNodeID(fields[0], former)
+			if fields[1] != "" {
+				return nil
+			}
+			return meta.Put([]byte("node"), joinRecord(nodeID, path, host, fields[3]))
+		}
+		former = append(former, fields[0])
+	}
+	setNodeID(randomName(), former)
+	return meta.Put([]byte("node"), joinRecord(nodeID, path, host, strings.Join(former, ",")))
+}
+
+func setNodeID(id string, former []string) {
+	nodeID = id
+	ownNodes = map[string]bool{id: true}
+	for _, f := range former {
+		ownNodes[f] = true
+	}
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	return formatUUID(b)
+}
+
+// legacyUUID derives a UUID for a snippet saved before UUIDs existed, so
+// the same legacy library gets the same UUIDs on every machine.
+func legacyUUID(vault string, s snippet) string {
+	sum := sha256.Sum256([]byte(vault + "|" + s.text + "|" + strconv.FormatInt(s.created, 10)))
+	b := sum[:16]
+	b[6] = b[6]&0x0f | 0x50
+	return formatUUID(b)
+}
+
+func formatUUID(b []byte) string {
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// fieldValues returns the clocked fields as strings.
+func (s snippet) fieldValues() map[string]string {
+	return map[string]string{
+		"text":     s.text,
+		"tag":      s.tag,
+		"alias":    s.alias,
+		"pinned":   strconv.FormatBool(s.pinned),
+		"secret":   strconv.FormatBool(s.secret),
+		"archived": strconv.FormatBool(s.archived),
+	}
+}
+
+// setField sets a clocked field from its string form. New text drops the
+// ciphertext it was read with, so encode seals the new value.
+func (s *snippet) setField(field, value string) {
+	switch field {
+	case "text":
+		if value != s.text {
+			s.sealed = ""
+		}
+		s.text = value
+	case "tag":
+		s.tag = value
+	case "alias":
+		s.alias = value
+	case "pinned":
+		s.pinned = value == "true"
+	case "secret":
+		s.secret = value == "true"
+	case "archived":
+		s.archived = value == "true"
+	}
+}
+
+// stampClocks returns s's clocks with a new stamp on every field that
+// differs from what was loaded. New snippets stamp every field and the
+// vault.
+func (s snippet) stampClocks() map[string]stamp {
+	clocks := map[string]stamp{}
+	for f, c := range s.clocks {
+		clocks[f] = c
+	}
+	now := newStamp()
+	current := s.fieldValues()
+	for _, f := range clockFields {
+		if s.loaded == nil || s.loaded[f] != current[f] {
+			clocks[f] = now
+		}
+	}
+	if s.loaded == nil {
+		if _, ok := clocks["vault"]; !ok {
+			clocks["vault"] = now
+		}
+	}
+	return clocks
+}
+
+func encodeClocks(clocks map[string]stamp) string {
+	var parts []string
+	for f, c := range clocks {
+		parts = append(parts, f+"="+c.String())
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ";")
+}
+
+func decodeClocks(v string) map[string]stamp {
+	clocks := map[string]stamp{}
+	for _, part := range strings.Split(v, ";") {
+		if f, c, ok := strings.Cut(part, "="); ok {
+			clocks[f] = parseStamp(c)
+		}
+	}
+	return clocks
+}
+
+// removeSnippet deletes a snippet and its alias without a tombstone, for
+// moves and for deletes that came from another machine.
+func removeSnippet(tx *bbolt.Tx, vault string, s snippet) error {
+	if err := releaseAlias(tx, vault, s.alias, s.id); err != nil {
+		return err
+	}
+	return tx.Bucket(snippetsKey(vault)).Delete([]byte(s.id))
+}
+
+// dropSnippet deletes a snippet and its alias and leaves a tombstone so
+// syncs delete it elsewhere too.
+func dropSnippet(tx *bbolt.Tx, vault string, s snippet) error {
+	if err := removeSnippet(tx, vault, s); err != nil {
+		return err
+	}
+	if s.uuid == "" {
+		return nil
+	}
+	return putTombstone(tx, s.uuid, newStamp())
+}
+
+// putTombstone records that uuid was deleted at st, keeping the newest.
+func putTombstone(tx *bbolt.Tx, uuid string, st stamp) error {
+	tombs, err := tx.CreateBucketIfNotExists([]byte("tombstones"))
+	if err != nil {
+		return err
+	}
+	if old := tombs.Get([]byte(uuid)); old != nil && !st.after(parseStamp(string(old))) {
+		return nil
+	}
+	return tombs.Put([]byte(uuid), []byte(st.String()))
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/bbolt"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// ------------------ ENCRYPTION ------------------
+
+// An encrypted database seals every snippet's text with XChaCha20-Poly1305
+// under a key derived from a passphrase with scrypt. Tags, aliases and
+// counts stay readable so indexes and sync state keep working without the
+// key. The key is never stored: commands get it from the daemon's session
+// ('grb unlock'), from GRB_PASSPHRASE, or by asking on the terminal.
+//
+// DB schema (meta bucket): "crypto" -> scrypt|N|r|p|salt|check, where check
+// is a sealed known value used to verify the passphrase.
+
+// ErrSealed means the database is encrypted and no key is available.
+var ErrSealed = errors.New("snippets are encrypted and grb is locked")
+
+// sealedPrefix marks sealed text fields: the prefix, then base64 of the
+// nonce followed by the ciphertext.
+const sealedPrefix = "enc1:"
+
+// lockedText stands in for text that can't be decrypted.
+const lockedText = "🔒 (locked)"
+
+const checkValue = "grb"
+
+type kdfParams struct {
+	n, r, p int
+	salt    []byte
+	check   string
+}
+
+var (
+	encryption *kdfParams // set by initDB when the DB is encrypted
+
+	keyMu     sync.Mutex
+	cipherKey []byte // the unlocked key; the daemon clears it when the session ends
+)
+
+func sessionKey() []byte {
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	return cipherKey
+}
+
+func setSessionKey(key []byte) {
+	keyMu.Lock()
+	cipherKey = key
+	keyMu.Unlock()
+}
+
+// loadEncryption reads the KDF parameters from the "meta" bucket.
+func loadEncryption(tx *bbolt.Tx) {
+	encryption = nil
+	meta := tx.Bucket([]byte("meta"))
+	if meta == nil {
+		return
+	}
+	v := meta.Get([]byte("crypto"))
+	if v == nil {
+		return
+	}
+	fields := splitRecord(v)
+	if len(fields) < 6 || fields[0] != "scrypt" {
+		return
+	}
+	p := &kdfParams{check: fields[5]}
+	p.n, _ = strconv.Atoi(fields[1])
+	p.r, _ = strconv.Atoi(fields[2])
+	p.p, _ = strconv.Atoi(fields[3])
+	p.salt, _ = hex.DecodeString(fields[4])
+	encryption = p
+}
+
+func (p *kdfParams) encode() []byte {
+	return joinRecord("scrypt", strconv.Itoa(p.n), strconv.Itoa(p.r), strconv.Itoa(p.p),
+		hex.EncodeToString(p.salt), p.check)
+}
+
+func (p *kdfParams) deriveKey(passphrase string) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), p.salt, p.n, p.r, p.p, chacha20poly1305.KeySize)
+}
+
+// unlock derives the key for passphrase and checks it against the DB.
+func (p *kdfParams) unlock(passphrase string) ([]byte, error) {
+	key, err := p.deriveKey(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if v, err := openWith(key, p.check); err != nil || v != checkValue {
+		return nil, fmt.Errorf("wrong passphrase")
+	}
+	return key, nil
+}
+
+func sealWith(key []byte, text string) string {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		panic(err) // the key always has the right size
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(text)+aead.Overhead())
+	rand.Read(nonce)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(text), nil))
+}
+
+func openWith(key []byte, sealed string) (string, error) {
+	raw, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil {
+		return "", err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < aead.NonceSize() {
+		return "", fmt.Errorf("sealed text too short")
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	return string(plain), err
+}
+
+// openText decrypts a stored text field. Without the key it returns a
+// placeholder; the sealed value is returned too so encode can write it
+// back untouched.
+func openText(field string) (text, sealed string) {
+	if encryption == nil || !strings.HasPrefix(field, sealedPrefix) {
+		return field, ""
+	}
+	key := sessionKey()
+	if key == nil {
+		return lockedText, field
+	}
+	text, err := openWith(key, field)
+	if err != nil {
+		return lockedText, field
+	}
+	return text, field
+}
+
+// sealText encrypts a text field for storage. Commands get the key in
+// PersistentPreRunE; writers that run without one (doctor --fix on a
+// locked DB, the daemon) get ErrSealed for any text they changed.
+func sealText(s snippet) (string, error) {
+	if encryption == nil {
+		return s.text, nil
+	}
+	if s.sealed != "" && s.loaded != nil && s.text == s.loaded["text"] {
+		return s.sealed, nil
+	}
+	key := sessionKey()
+	if key == nil {
+		return "", ErrSealed
+	}
+	return sealWith(key, s.text), nil
+}
+
+// readPassphrase asks for a passphrase on the terminal without echo.
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimRight(line, "\r\n"), err
+	}
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(pass), err
+}
+
+// openSession makes the key available to a command on an encrypted DB:
+// from the daemon's session, GRB_PASSPHRASE or a prompt. Commands
+// annotated crypto=none (lock, unlock, encrypt, daemon) skip it.
+func openSession(cmd *cobra.Command) error {
+	if encryption == nil || sessionKey() != nil {
+		return nil
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations["crypto"] == "none" {
+			return nil
+		}
+	}
+	if key := daemonKey(); key != nil {
+		setSessionKey(key)
+		return nil
+	}
+	pass := os.Getenv("GRB_PASSPHRASE")
+	if pass == "" {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return ErrSealed
+		}
+		var err error
+		if pass, err = readPassphrase("🔒 Passphrase: "); err != nil {
+			return err
+		}
+	}
+	key, err := encryption.unlock(pass)
+	if err != nil {
+		return err
+	}
+	setSessionKey(key)
+	return nil
+}
+
+// unlockSession checks the passphrase and hands the key to the daemon for
+// ttl.
+func unlockSession(ttl time.Duration) error {
+	if encryption == nil {
+		return usagef("the database is not encrypted; see 'grb encrypt'")
+	}
+	pass := os.Getenv("GRB_PASSPHRASE")
+	if pass == "" {
+		var err error
+		if pass, err = readPassphrase("🔒 Passphrase: "); err != nil {
+			return err
+		}
+	}
+	key, err := encryption.unlock(pass)
+	if err != nil {
+		return err
+	}
+	reply, err := daemonRequest("unlock", hex.EncodeToString(key), strconv.Itoa(int(ttl.Seconds())))
+	if err != nil {
+		return err
+	}
+	if reply != "ok" {
+		return fmt.Errorf("daemon refused the session: %s", reply)
+	}
+	say(theme.success, "🔓 Unlocked for %s", ttl)
+	return nil
+}
+
+// lockSession makes the daemon forget the key.
+func lockSession() error {
+	conn := dialDaemon()
+	if conn == nil {
+		say(theme.success, "🔒 Locked (no daemon session was open)")
+		return nil
+	}
+	conn.Close()
+	if _, err := daemonRequest("lock"); err != nil {
+		return err
+	}
+	say(theme.success, "🔒 Locked")
+	return nil
+}
+
+// daemonKey asks a running daemon for the session key.
+func daemonKey() []byte {
+	reply, err := daemonRequest("key")
+	if err != nil {
+		return nil
+	}
+	hexKey, ok := strings.CutPrefix(reply, "key ")
+	if !ok {
+		return nil
+	}
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+// encryptDB turns encryption on and seals every snippet in one
+// transaction, then compacts the file so no plaintext is left in free
+// pages. A DB that already has snippets needs migrate.
+func encryptDB(migrate bool) error {
+	if encryption != nil {
+		return usagef("the database is already encrypted")
+	}
+	type row struct {
+		vault string
+		s     snippet
+	}
+	var rows []row
+	db.View(func(tx *bbolt.Tx) error {
+		for _, name := range listVaults(tx) {
+			tx.Bucket(snippetsKey(name)).ForEach(func(k, v []byte) error {
+				rows = append(rows, row{name, parseSnippet(k, v)})
+				return nil
+			})
+		}
+		return nil
+	})
+	if len(rows) > 0 && !migrate {
+		return usagef("the database has %d snippet(s); run 'grb encrypt --migrate' to encrypt them", len(rows))
+	}
+
+	pass := os.Getenv("GRB_PASSPHRASE")
+	if pass == "" {
+		var err error
+		if pass, err = readPassphrase("🔒 New passphrase: "); err != nil {
+			return err
+		}
+		again, err := readPassphrase("🔒 Repeat passphrase: ")
+		if err != nil {
+			return err
+		}
+		if again != pass {
+			return usagef("passphrases don't match")
+		}
+	}
+	if pass == "" {
+		return usagef("passphrase must not be empty")
+	}
+
+	params := &kdfParams{n: 1 << 15, r: 8, p: 1, salt: make([]byte, 16)}
+	rand.Read(params.salt)
+	key, err := params.deriveKey(pass)
+	if err != nil {
+		return err
+	}
+	params.check = sealWith(key, checkValue)
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists([]byte("meta"))
+		if err != nil {
+			return err
+		}
+		if err := meta.Put([]byte("crypto"), params.encode()); err != nil {
+			return err
+		}
+		encryption = params
+		setSessionKey(key)
+		for _, r := range rows {
+			if err := putSnippet(tx.Bucket(snippetsKey(r.vault)), r.s.id, r.s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		encryption = nil
+		return err
+	}
+	if err := compactDB(); err != nil {
+		return fmt.Errorf("encrypted, but compacting failed (old plaintext may remain in free pages): %w", err)
+	}
+	say(theme.success, "🔒 Encrypted %d snippet(s)", len(rows))
+
+	// Backups taken before now hold the plaintext. The daemon's snapshots
+	// are replaced by encrypted ones anyway; the others are the user's.
+	removed, err := removeSnapshots()
+	if err != nil {
+		return fmt.Errorf("encrypted, but removing the plaintext snapshots failed: %w", err)
+	}
+	if removed > 0 {
+		fmt.Printf("   🗑 Removed %d plaintext daemon snapshot(s)\n", removed)
+	}
+	if kept := listBackups(""); len(kept) > 0 {
+		fmt.Printf("%s These backups were taken before encryption and are still plaintext:\n", theme.highlight.Sprint("⚠"))
+		for _, f := range kept {
+			fmt.Println("   " + f.path)
+		}
+		fmt.Println("💡 Tip: Delete them, or take a new one with 'grb backup'")
+	}
+	return nil
+}
+
+// compactDB rewrites the DB file into a fresh one, dropping free pages.
+func compactDB() error {
+	path := getDBPath()
+	tmp := path + ".compact"
+	dst, err := bbolt.Open(tmp, 0600, nil)
+	if err != nil {
+		return err
+	}
+	if err := bbolt.Compact(dst, db, 0); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	releaseDB()
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return acquireDB()
+}
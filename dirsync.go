@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// ------------------ FOLDER SYNC ------------------
+
+// 'grb sync --dir' merges libraries through any shared folder (Dropbox,
+// Syncthing, a network drive). Each DB appends its own writes to
+// <dir>/<node>.jsonl, one field change per line, and reads the other
+// nodes' logs from where it stopped last time. Because every line carries
+// the field's clock, each machine keeps the newest value per field and they
+// all end up with the same library whatever order the logs arrive in. A
+// delete wins over any edit. Files are only ever appended to by their own
+// node, so the folder tool never has to merge anything.
+//
+// DB schema (dirsync bucket): "<dir>|sent" -> last exported stamp time,
+// "<dir>|<node>" -> bytes of that node's log already applied.
+
+type changeOp struct {
+	UUID  string `json:"uuid"`
+	Field string `json:"field"` // vault, text, tag, alias, pinned, secret, archived or deleted
+	Value string `json:"value,omitempty"`
+	At    int64  `json:"at"`
+	Node  string `json:"node"`
+}
+
+func (op changeOp) stamp() stamp {
+	return stamp{op.At, op.Node}
+}
+
+type dirSyncReport struct {
+	sent, applied           int
+	added, updated, deleted int
+	conflicts               []string
+}
+
+// runDirSync exchanges change logs with the shared folder dir.
+func runDirSync(dir string) error {
+	if err := checkPlaintextSync(); err != nil {
+		return err
+	}
+	dir, err := filepath.Abs(expandHome(dir))
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return usagef("%s is not a directory", dir)
+	}
+
+	var r dirSyncReport
+	err = db.Update(func(tx *bbolt.Tx) error {
+		state, err := tx.CreateBucketIfNotExists([]byte("dirsync"))
+		if err != nil {
+			return err
+		}
+		if err := exportChanges(tx, state, dir, &r); err != nil {
+			return err
+		}
+		return importChanges(tx, state, dir, &r)
+	})
+	if err != nil {
+		return err
+	}
+
+	say(theme.success, "📂 Synced with %s", dir)
+	fmt.Printf("   ⬆ %d change(s) written\n", r.sent)
+	fmt.Printf("   ⬇ %d change(s) applied: %d added, %d field(s) updated, %d deleted\n", r.applied, r.added, r.updated, r.deleted)
+	if len(r.conflicts) > 0 {
+		return fmt.Errorf("%w: %d change(s) not applied:\n  %s", ErrSyncConflict, len(r.conflicts), strings.Join(r.conflicts, "\n  "))
+	}
+	return nil
+}
+
+// clockLegacyOnce runs clockLegacySnippets the first time a database is
+// opened by a grb that has clocks, before any write can give a legacy
+// snippet a random UUID.
+func clockLegacyOnce(tx *bbolt.Tx) error {
+	meta := tx.Bucket([]byte("meta"))
+	if meta.Get([]byte("clocked")) != nil {
+		return nil
+	}
+	if err := clockLegacySnippets(tx); err != nil {
+		return err
+	}
+	return meta.Put([]byte("clocked"), []byte("1"))
+}
+
+// clockLegacySnippets gives snippets written before clocks existed a UUID
+// and a clock on every field, so they can be exported. The UUID comes from
+// the snippet's content, so copies of one legacy library merge instead of
+// doubling; only a second identical snippet in the same DB gets a random one.
+func clockLegacySnippets(tx *bbolt.Tx) error {
+	now := newStamp()
+	taken := map[string]bool{}
+	for _, name := range listVaults(tx) {
+		tx.Bucket(snippetsKey(name)).ForEach(func(k, v []byte) error {
+			taken[parseSnippet(k, v).uuid] = true
+			return nil
+		})
+	}
+	for _, name := range listVaults(tx) {
+		b := tx.Bucket(snippetsKey(name))
+		var stale []snippet
+		b.ForEach(func(k, v []byte) error {
+			s := parseSnippet(k, v)
+			changed := s.uuid == ""
+			if changed {
+				s.uuid = legacyUUID(name, s)
+				if taken[s.uuid] {
+					s.uuid = newUUID()
+				}
+				taken[s.uuid] = true
+			}
+			if s.clocks == nil {
+				s.clocks = map[string]stamp{}
+			}
+			for _, f := range append([]string{"vault"}, clockFields...) {
+				if _, ok := s.clocks[f]; !ok {
+					s.clocks[f] = now
+					changed = true
+				}
+			}
+			if changed {
+				stale = append(stale, s)
+			}
+			return nil
+		})
+		for _, s := range stale {
+			if err := putSnippet(b, s.id, s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportChanges appends this node's field writes and deletes made since
+// the last sync with dir to its log.
+func exportChanges(tx *bbolt.Tx, state *bbolt.Bucket, dir string, r *dirSyncReport) error {
+	sentKey := []byte(dir + "|sent")
+	sent, _ := strconv.ParseInt(string(state.Get(sentKey)), 10, 64)
+
+	var ops []changeOp
+	add := func(uuid, field, value string, st stamp) {
+		if ownNodes[st.node] && st.at > sent {
+			ops = append(ops, changeOp{uuid, field, value, st.at, st.node})
+		}
+	}
+	for _, name := range listVaults(tx) {
+		tx.Bucket(snippetsKey(name)).ForEach(func(k, v []byte) error {
+			s := parseSnippet(k, v)
+			add(s.uuid, "vault", name, s.clocks["vault"])
+			values := s.fieldValues()
+			for _, f := range clockFields {
+				add(s.uuid, f, values[f], s.clocks[f])
+			}
+			return nil
+		})
+	}
+	if tombs := tx.Bucket([]byte("tombstones")); tombs != nil {
+		tombs.ForEach(func(k, v []byte) error {
+			add(string(k), "deleted", "", parseStamp(string(v)))
+			return nil
+		})
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+
+	sort.Slice(ops, func(i, j int) bool { return ops[j].stamp().after(ops[i].stamp()) })
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, op := range ops {
+		if err := enc.Encode(op); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(filepath.Join(dir, nodeID+".jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	r.sent = len(ops)
+	return state.Put(sentKey, []byte(strconv.FormatInt(ops[len(ops)-1].At, 10)))
+}
+
+// importChanges reads the other nodes' logs past what was already applied
+// and merges them in clock order.
+func importChanges(tx *bbolt.Tx, state *bbolt.Bucket, dir string, r *dirSyncReport) error {
+	logs, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return err
+	}
+	var ops []changeOp
+	for _, file := range logs {
+		node := strings.TrimSuffix(filepath.Base(file), ".jsonl")
+		if node == nodeID {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		readKey := []byte(dir + "|" + node)
+		offset, _ := strconv.Atoi(string(state.Get(readKey)))
+		if offset > len(data) {
+			offset = 0 // the log was replaced; re-reading it is harmless
+		}
+		// A line still being written by the folder tool has no newline yet.
+		end := bytes.LastIndexByte(data, '\n') + 1
+		if end <= offset {
+			continue
+		}
+		for _, line := range bytes.Split(data[offset:end-1], []byte("\n")) {
+			var op changeOp
+			if err := json.Unmarshal(line, &op); err != nil || op.UUID == "" {
+				r.conflicts = append(r.conflicts, fmt.Sprintf("%s: skipped malformed line", filepath.Base(file)))
+				continue
+			}
+			ops = append(ops, op)
+		}
+		if err := state.Put(readKey, []byte(strconv.Itoa(end))); err != nil {
+			return err
+		}
+	}
+
+	sort.Slice(ops, func(i, j int) bool { return ops[j].stamp().after(ops[i].stamp()) })
+	for _, op := range ops {
+		if err := applyChange(tx, op, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findUUID returns the vault and snippet with the given UUID.
+func findUUID(tx *bbolt.Tx, uuid string) (string, snippet, bool) {
+	for _, name := range listVaults(tx) {
+		c := tx.Bucket(snippetsKey(name)).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if s := parseSnippet(k, v); s.uuid == uuid {
+				return name, s, true
+			}
+		}
+	}
+	return "", snippet{}, false
+}
+
+// applyChange merges one remote field write: it wins only if its clock is
+// newer than the local one, and nothing is applied to deleted snippets.
+func applyChange(tx *bbolt.Tx, op changeOp, r *dirSyncReport) error {
+	if tombs := tx.Bucket([]byte("tombstones")); tombs != nil && tombs.Get([]byte(op.UUID)) != nil && op.Field != "deleted" {
+		return nil
+	}
+	name, s, ok := findUUID(tx, op.UUID)
+
+	if op.Field == "deleted" {
+		if ok {
+			if err := removeSnippet(tx, name, s); err != nil {
+				return err
+			}
+			r.deleted++
+		}
+		r.applied++
+		return putTombstone(tx, op.UUID, op.stamp())
+	}
+
+	if ok && !op.stamp().after(s.clocks[op.Field]) {
+		return nil
+	}
+	if !ok {
+		name = defaultVault
+		if op.Field == "vault" {
+			name = op.Value
+		}
+		if err := ensureVault(tx, name); err != nil {
+			return err
+		}
+		s = snippet{uuid: op.UUID, created: time.Now().Unix(), clocks: map[string]stamp{}}
+		s.loaded = s.fieldValues()
+		id, _ := tx.Bucket(snippetsKey(name)).NextSequence()
+		s.id = fmt.Sprintf("%d", id)
+		r.added++
+	}
+
+	switch op.Field {
+	case "vault":
+		if op.Value == name {
+			break
+		}
+		if err := ensureVault(tx, op.Value); err != nil {
+			return err
+		}
+		id, _ := tx.Bucket(snippetsKey(op.Value)).NextSequence()
+		newID := fmt.Sprintf("%d", id)
+		if err := claimAlias(tx, op.Value, s.alias, newID); err != nil {
+			return aliasConflict(op, err, r)
+		}
+		if err := removeSnippet(tx, name, s); err != nil {
+			return err
+		}
+		if err := moveEvents(tx, name, s.id, op.Value, newID); err != nil {
+			return err
+		}
+		name, s.id = op.Value, newID
+	case "alias":
+		if err := claimAlias(tx, name, op.Value, s.id); err != nil {
+			return aliasConflict(op, err, r)
+		}
+		if op.Value != s.alias {
+			if err := releaseAlias(tx, name, s.alias, s.id); err != nil {
+				return err
+			}
+		}
+		s.alias = op.Value
+	default:
+		s.setField(op.Field, op.Value)
+	}
+	if ok {
+		r.updated++
+	}
+	r.applied++
+	s.clocks[op.Field] = op.stamp()
+	s.loaded = s.fieldValues()
+	return putSnippet(tx.Bucket(snippetsKey(name)), s.id, s)
+}
+
+// ensureVault creates a vault that so far only exists on another machine.
+func ensureVault(tx *bbolt.Tx, name string) error {
+	if err := validateVault(name); err != nil {
+		return err
+	}
+	if _, err := tx.CreateBucketIfNotExists(snippetsKey(name)); err != nil {
+		return err
+	}
+	_, err := tx.CreateBucketIfNotExists(aliasesKey(name))
+	return err
+}
+
+// aliasConflict records a remote alias or move that clashes with an alias
+// taken here; the rest of the snippet still syncs. Other errors are
+// returned as is.
+func aliasConflict(op changeOp, err error, r *dirSyncReport) error {
+	if !errors.Is(err, ErrAliasTaken) {
+		return err
+	}
+	r.conflicts = append(r.conflicts, fmt.Sprintf("snippet %s: %v", op.UUID, err))
+	return nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+func copyDB(t *testing.T, src, dst string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func dirSync(t *testing.T, dir string) {
+	t.Helper()
+	if err := runDirSync(dir); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirSyncRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	shared := t.TempDir()
+	a, b := filepath.Join(dir, "a.db"), filepath.Join(dir, "b.db")
+
+	useDB(t, a)
+	for _, alias := range []string{"one", "two"} {
+		if _, err := createSnippet("echo "+alias, "ops", alias, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirSync(t, shared)
+
+	useDB(t, b)
+	dirSync(t, shared)
+	if got := library(t); len(got) != 2 {
+		t.Fatalf("b after first sync = %v", got)
+	}
+	editStored(t, "one", func(s *snippet) { s.tag = "ops,b" })
+	err := db.Update(func(tx *bbolt.Tx) error {
+		s, _ := findSnippet(tx, vault, "two")
+		return dropSnippet(tx, vault, s)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirSync(t, shared)
+
+	useDB(t, a)
+	editStored(t, "one", func(s *snippet) { s.text = "echo uno" })
+	dirSync(t, shared)
+	libA := library(t)
+
+	useDB(t, b)
+	dirSync(t, shared)
+	libB := library(t)
+
+	if !reflect.DeepEqual(libA, libB) {
+		t.Fatalf("libraries differ:\na: %v\nb: %v", libA, libB)
+	}
+	if len(libA) != 1 || libA[0]["text"] != "echo uno" || libA[0]["tag"] != "ops,b" {
+		t.Fatalf("library = %v, want 'one' with both edits and 'two' deleted", libA)
+	}
+}
+
+func TestCopiedDBGetsItsOwnNode(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.db"), filepath.Join(dir, "b.db")
+
+	useDB(t, a)
+	original := nodeID
+	releaseDB()
+	useDB(t, a)
+	if nodeID != original {
+		t.Fatalf("reopening changed the node id from %s to %s", original, nodeID)
+	}
+	releaseDB()
+
+	copyDB(t, a, b)
+	useDB(t, b)
+	if nodeID == original {
+		t.Fatalf("the copy kept node id %s", original)
+	}
+	if !ownNodes[original] {
+		t.Fatalf("the copy forgot its former node id %s", original)
+	}
+}
+
+func TestLegacyLibraryMergesAcrossCopies(t *testing.T) {
+	dir := t.TempDir()
+	shared := t.TempDir()
+	a, b := filepath.Join(dir, "a.db"), filepath.Join(dir, "b.db")
+
+	// A library from before UUIDs and clocks, copied to a second machine.
+	useDB(t, a)
+	err := db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket([]byte("meta")).Delete([]byte("clocked")); err != nil {
+			return err
+		}
+		b := tx.Bucket(snippetsKey(vault))
+		if err := b.SetSequence(1); err != nil {
+			return err
+		}
+		return b.Put([]byte("1"), []byte("echo legacy|ops||false|0|1700000000"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseDB()
+	copyDB(t, a, b)
+
+	useDB(t, a)
+	dirSync(t, shared)
+	useDB(t, b)
+	dirSync(t, shared)
+	if got := library(t); len(got) != 1 {
+		t.Fatalf("library = %v, want the legacy snippet once", got)
+	}
+}
+
+func TestEncryptedDirSyncRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	shared := t.TempDir()
+	a, b := filepath.Join(dir, "a.db"), filepath.Join(dir, "b.db")
+	t.Setenv("GRB_PASSPHRASE", "pw")
+	syncPlaintext = true
+	t.Cleanup(func() { syncPlaintext = false })
+
+	useDB(t, a)
+	if err := encryptDB(false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createSnippet("echo one", "", "one", false); err != nil {
+		t.Fatal(err)
+	}
+	dirSync(t, shared)
+
+	useDB(t, b)
+	dirSync(t, shared)
+	editStored(t, "one", func(s *snippet) { s.text = "echo uno" })
+	if _, err := createSnippet("echo two", "", "two", false); err != nil {
+		t.Fatal(err)
+	}
+	dirSync(t, shared)
+
+	useDB(t, a)
+	dirSync(t, shared)
+	got := library(t)
+	if len(got) != 2 || got[0]["text"] != "echo two" || got[1]["text"] != "echo uno" {
+		t.Fatalf("a after sync = %v", got)
+	}
+	db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(snippetsKey(vault)).ForEach(func(k, v []byte) error {
+			if strings.Contains(string(v), "echo") {
+				t.Errorf("snippet %s is stored in plaintext: %q", k, v)
+			}
+			return nil
+		})
+	})
+
+	// Without the key, changed text can't be sealed.
+	setSessionKey(nil)
+	if _, err := createSnippet("echo three", "", "", false); !errors.Is(err, ErrSealed) {
+		t.Fatalf("saving without the key: err = %v, want ErrSealed", err)
+	}
+}
+
+func TestDirSyncMoveKeepsUsage(t *testing.T) {
+	dir := t.TempDir()
+	shared := t.TempDir()
+	a, b := filepath.Join(dir, "a.db"), filepath.Join(dir, "b.db")
+
+	useDB(t, a)
+	if _, err := createSnippet("echo one", "", "one", false); err != nil {
+		t.Fatal(err)
+	}
+	dirSync(t, shared)
+
+	useDB(t, b)
+	dirSync(t, shared)
+	err := db.Update(func(tx *bbolt.Tx) error {
+		s, _ := findSnippet(tx, vault, "one")
+		return logEvent(tx, eventCopy, vault, s.id)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	useDB(t, a)
+	if err := createVault("team"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := moveSnippet("one", "team"); err != nil {
+		t.Fatal(err)
+	}
+	dirSync(t, shared)
+
+	useDB(t, b)
+	dirSync(t, shared)
+	db.View(func(tx *bbolt.Tx) error {
+		s, ok := findSnippet(tx, "team", "one")
+		events := eventsSince(tx, time.Unix(0, 0))
+		if !ok || len(events) != 1 || events[0].vault != "team" || events[0].id != s.id {
+			t.Errorf("events = %+v, want the copy on team/%s", events, s.id)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.etcd.io/bbolt"
+)
+
+// ------------------ DOCTOR ------------------
+
+// 'grb doctor' walks every bucket and reports records that don't decode
+// cleanly, records in the legacy (unescaped, pre-UUID) format, alias index
+// entries that point nowhere or are missing, aliases claimed by more
+// than one snippet, and entries of the usage log, saved views, sync state
+// and tombstones that grb can't read. With --fix everything is repaired
+// in one transaction, so a failed repair changes nothing.
+
+// diagnose checks tx and, if fix is set, repairs what it finds. It
+// returns one line per problem.
+func diagnose(tx *bbolt.Tx, fix bool) ([]string, error) {
+	var problems []string
+	report := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	seenUUID := map[string]string{}
+	for _, name := range listVaults(tx) {
+		b := tx.Bucket(snippetsKey(name))
+
+		// Records: collect first, bbolt can't write while iterating.
+		var rewrite []snippet
+		owners := map[string][]string{} // alias -> ids, in key order
+		b.ForEach(func(k, v []byte) error {
+			s := parseSnippet(k, v)
+			where := name + "/" + string(k)
+			dirty := false
+			for _, p := range recordProblems(v) {
+				report("%s: %s", where, p)
+				dirty = true
+			}
+			if s.alias != "" {
+				if err := validateAlias(s.alias); err != nil {
+					report("%s: invalid alias %q, dropping it", where, s.alias)
+					s.alias = ""
+					dirty = true
+				}
+			}
+			if s.uuid != "" {
+				if other, ok := seenUUID[s.uuid]; ok {
+					report("%s: same UUID as %s, giving it a new one", where, other)
+					s.uuid = ""
+					dirty = true
+				} else {
+					seenUUID[s.uuid] = where
+				}
+			}
+			if s.alias != "" {
+				owners[s.alias] = append(owners[s.alias], s.id)
+			}
+			if dirty {
+				rewrite = append(rewrite, s)
+			}
+			return nil
+		})
+
+		// Aliases: the index owner keeps a duplicated alias, or the lowest id.
+		aliases := make([]string, 0, len(owners))
+		for alias := range owners {
+			aliases = append(aliases, alias)
+		}
+		sort.Strings(aliases)
+		idx := tx.Bucket(aliasesKey(name))
+		drop := map[string]bool{}
+		for _, alias := range aliases {
+			ids := owners[alias]
+			if len(ids) < 2 {
+				continue
+			}
+			sort.Slice(ids, func(i, j int) bool { return lessID(ids[i], ids[j]) })
+			keep := ids[0]
+			if idx != nil {
+				for _, id := range ids {
+					if string(idx.Get([]byte(alias))) == id {
+						keep = id
+					}
+				}
+			}
+			for _, id := range ids {
+				if id != keep {
+					report("%s/%s: alias %q is also used by [%s], dropping it here", name, id, alias, keep)
+					drop[id] = true
+				}
+			}
+		}
+		indexDirty := len(drop) > 0
+		if idx == nil {
+			report("vault %s: alias index is missing", name)
+			indexDirty = true
+		} else {
+			idx.ForEach(func(alias, id []byte) error {
+				v := b.Get(id)
+				if v == nil {
+					report("vault %s: alias %q points to missing snippet [%s]", name, alias, id)
+					indexDirty = true
+				} else if s := parseSnippet(id, v); s.alias != string(alias) {
+					report("vault %s: alias %q points to [%s], which has alias %q", name, alias, id, s.alias)
+					indexDirty = true
+				}
+				return nil
+			})
+			for _, alias := range aliases {
+				if idx.Get([]byte(alias)) == nil {
+					report("vault %s: alias %q of [%s] is not indexed", name, alias, owners[alias][0])
+					indexDirty = true
+				}
+			}
+		}
+
+		if !fix {
+			continue
+		}
+		for id := range drop {
+			s := parseSnippet([]byte(id), b.Get([]byte(id)))
+			for _, r := range rewrite {
+				if r.id == id {
+					s = r
+				}
+			}
+			s.alias = ""
+			rewrite = append(rewrite, s)
+		}
+		for _, s := range rewrite {
+			if err := putSnippet(b, s.id, s); err != nil {
+				return nil, err
+			}
+		}
+		if indexDirty {
+			if err := rebuildAliasIndex(tx, name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Alias indexes of vaults that no longer exist.
+	var orphans []string
+	tx.ForEach(func(bucket []byte, _ *bbolt.Bucket) error {
+		if n, ok := strings.CutPrefix(string(bucket), "aliases."); ok && !vaultExists(tx, n) {
+			report("alias index %q has no vault", n)
+			orphans = append(orphans, string(bucket))
+		}
+		return nil
+	})
+	meta := tx.Bucket([]byte("meta"))
+	badVault := false
+	if meta != nil {
+		if used := meta.Get([]byte("vault")); used != nil && !vaultExists(tx, string(used)) {
+			report("current vault %q does not exist", used)
+			badVault = true
+		}
+	}
+
+	var bad []badEntry
+	for _, c := range stateChecks {
+		b := tx.Bucket([]byte(c.bucket))
+		if b == nil {
+			continue
+		}
+		b.ForEach(func(k, v []byte) error {
+			if p := c.problem(k, v); p != "" {
+				report("%s: %s", c.bucket, p)
+				bad = append(bad, badEntry{b, bytes.Clone(k), c.repair})
+			}
+			return nil
+		})
+	}
+
+	if fix {
+		for _, e := range bad {
+			repair := e.repair
+			if repair == nil {
+				repair = (*bbolt.Bucket).Delete
+			}
+			if err := repair(e.bucket, e.key); err != nil {
+				return nil, err
+			}
+		}
+		for _, bucket := range orphans {
+			if err := tx.DeleteBucket([]byte(bucket)); err != nil {
+				return nil, err
+			}
+		}
+		if badVault {
+			if err := meta.Delete([]byte("vault")); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return problems, nil
+}
+
+type badEntry struct {
+	bucket *bbolt.Bucket
+	key    []byte
+	repair func(b *bbolt.Bucket, k []byte) error
+}
+
+// stateChecks describe what is wrong with an entry of the buckets beside
+// the vaults. Unless a check has its own repair, --fix drops such
+// entries: the usage log loses an event, and a sync starts over from the
+// beginning of the log or file it lost its place in, which is harmless.
+var stateChecks = []struct {
+	bucket  string
+	problem func(k, v []byte) string
+	repair  func(b *bbolt.Bucket, k []byte) error
+}{
+	{"views", func(k, v []byte) string {
+		if !bytes.Contains(v, []byte("|")) {
+			return fmt.Sprintf("saved view %q is %q, not tag|query", k, v)
+		}
+		return ""
+	}, func(b *bbolt.Bucket, k []byte) error {
+		// Loading reads it as a tag, so keep it as one.
+		return b.Put(k, append(bytes.Clone(b.Get(k)), '|'))
+	}},
+	{"usage", func(k, v []byte) string {
+		if len(k) != 16 {
+			return fmt.Sprintf("event key %x is %d bytes, not 16", k, len(k))
+		}
+		if f := splitRecord(v); len(f) != 3 || f[0] == "" || f[1] == "" || f[2] == "" {
+			return fmt.Sprintf("event %x is %q, not kind|vault|id", k, v)
+		}
+		return ""
+	}, nil},
+	{"sync", func(k, v []byte) string {
+		if f := splitRecord(v); !bytes.Contains(k, []byte("/")) || len(f) != 2 || f[0] == "" || f[1] == "" {
+			return fmt.Sprintf("entry %q is %q, not file|hash", k, v)
+		}
+		return ""
+	}, nil},
+	{"dirsync", func(k, v []byte) string {
+		i := bytes.LastIndexByte(k, '|')
+		if n, err := strconv.ParseInt(string(v), 10, 64); i <= 0 || err != nil || n < 0 {
+			return fmt.Sprintf("entry %q is %q, not a position", k, v)
+		}
+		return ""
+	}, nil},
+	{"tombstones", func(k, v []byte) string {
+		at, node, _ := strings.Cut(string(v), "@")
+		if n, err := strconv.ParseInt(at, 10, 64); err != nil || n <= 0 || node == "" {
+			return fmt.Sprintf("tombstone of %s has stamp %q, restamping it", k, v)
+		}
+		return ""
+	}, func(b *bbolt.Bucket, k []byte) error {
+		// Keep the delete; it wins again from now on.
+		return b.Put(k, []byte(newStamp().String()))
+	}},
+}
+
+// recordProblems describes what is wrong with a stored record, if
+// anything.
+func recordProblems(v []byte) []string {
+	var problems []string
+	legacy := !bytes.HasSuffix(v, []byte("|"+recordMarker))
+	fields := splitRecord(v)
+	if len(fields) < 6 {
+		problems = append(problems, fmt.Sprintf("only %d of 6 fields", len(fields)))
+	}
+	get := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+		return ""
+	}
+	if p := get(3); p != "true" && p != "false" && p != "" {
+		problems = append(problems, fmt.Sprintf("pinned is %q", p))
+	}
+	if _, err := strconv.Atoi(get(4)); err != nil && get(4) != "" {
+		problems = append(problems, fmt.Sprintf("use count is %q", get(4)))
+	}
+	if _, err := strconv.ParseInt(get(5), 10, 64); err != nil && get(5) != "" {
+		problems = append(problems, fmt.Sprintf("timestamp is %q", get(5)))
+	}
+	switch {
+	case legacy:
+		problems = append(problems, "legacy record format")
+	case len(fields) < 8 || fields[6] == "":
+		problems = append(problems, "record has no UUID or clocks")
+	}
+	return problems
+}
+
+// lessID orders snippet ids numerically.
+func lessID(a, b string) bool {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return x < y
+}
+
+// runDoctor prints the problems found and, with fix, repairs them.
+func runDoctor(fix bool) error {
+	var problems []string
+	check := func(tx *bbolt.Tx) error {
+		var err error
+		problems, err = diagnose(tx, fix)
+		return err
+	}
+	var err error
+	if fix {
+		err = db.Update(check)
+	} else {
+		err = db.View(check)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		say(theme.success, "🩺 No problems found")
+		return nil
+	}
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Printf("%s (%d)\n", theme.accent.Sprint("🩺 Problems"), len(problems))
+	fmt.Println("─────────────────────────────────────────────")
+	for _, p := range problems {
+		fmt.Println("  • " + p)
+	}
+	if fix {
+		say(theme.success, "✅ Repaired %d problem(s)", len(problems))
+		return nil
+	}
+	return fmt.Errorf("%d problem(s) found; run 'grb doctor --fix' to repair them", len(problems))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+func TestDoctorChecksBookkeeping(t *testing.T) {
+	useDB(t, filepath.Join(t.TempDir(), "grb.db"))
+	if _, err := createSnippet("echo one", "", "one", false); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := map[string][2]string{
+		"views":      {"ops", "ops"},
+		"usage":      {"short", "copy|default|1"},
+		"sync":       {"default/1", "no-hash"},
+		"dirsync":    {"/shared|node", "-3"},
+		"tombstones": {"dead-uuid", "yesterday"},
+	}
+	err := db.Update(func(tx *bbolt.Tx) error {
+		for bucket, kv := range corrupt {
+			b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(kv[0]), []byte(kv[1])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var problems []string
+	diagnoseWith := func(fix bool) {
+		t.Helper()
+		err := db.Update(func(tx *bbolt.Tx) error {
+			var err error
+			problems, err = diagnose(tx, fix)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	diagnoseWith(false)
+	for bucket := range corrupt {
+		found := false
+		for _, p := range problems {
+			found = found || strings.HasPrefix(p, bucket+": ")
+		}
+		if !found {
+			t.Errorf("no problem reported in %s: %q", bucket, problems)
+		}
+	}
+	// The snippet and its alias are fine.
+	if len(problems) != len(corrupt) {
+		t.Errorf("problems = %q, want one per bucket", problems)
+	}
+
+	diagnoseWith(true)
+	diagnoseWith(false)
+	if len(problems) != 0 {
+		t.Fatalf("after --fix: %q", problems)
+	}
+	db.View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket([]byte("views")).Get([]byte("ops")); string(v) != "ops|" {
+			t.Errorf("view = %q, want it kept as a tag", v)
+		}
+		if v := tx.Bucket([]byte("tombstones")).Get([]byte("dead-uuid")); parseStamp(string(v)).node != nodeID {
+			t.Errorf("tombstone = %q, want it restamped", v)
+		}
+		if v := tx.Bucket([]byte("usage")).Get([]byte("short")); v != nil {
+			t.Errorf("short usage key kept: %q", v)
+		}
+		return nil
+	})
+}
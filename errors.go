@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+)
+
+// ------------------ ERRORS ------------------
+
+// Error categories returned by commands. Wrap them with %w to add detail;
+// main maps each one to its own exit code.
+var (
+	ErrNotFound             = errors.New("not found")
+	ErrAliasTaken           = errors.New("alias already in use")
+	ErrClipboardUnavailable = errors.New("clipboard unavailable")
+	ErrLocked               = errors.New("database is locked by another grb process")
+)
+
+// Exit codes, so scripts can tell failures apart.
+const (
+	exitError     = 1 // anything else, e.g. I/O or DB errors
+	exitUsage     = 2 // bad arguments or flags
+	exitNotFound  = 3
+	exitConflict  = 4 // alias already in use, sync conflict
+	exitClipboard = 5
+	exitLocked    = 6 // DB busy, or encrypted and locked
+)
+
+// usageError reports bad arguments.
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func usagef(format string, a ...interface{}) error {
+	return usageError{fmt.Sprintf(format, a...)}
+}
+
+// exitStatus passes a child process's exit code through 'grb run'
+// without printing anything.
+type exitStatus int
+
+func (e exitStatus) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+
+func exitCode(err error) int {
+	var usage usageError
+	var status exitStatus
+	switch {
+	case errors.As(err, &status):
+		return int(status)
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.Is(err, ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, ErrAliasTaken), errors.Is(err, ErrSyncConflict):
+		return exitConflict
+	case errors.Is(err, ErrClipboardUnavailable):
+		return exitClipboard
+	case errors.Is(err, ErrLocked), errors.Is(err, ErrSealed):
+		return exitLocked
+	}
+	return exitError
+}
+
+// reportError prints err, plus a tip for common cases, on stderr.
+func reportError(err error) {
+	var status exitStatus
+	if errors.As(err, &status) {
+		return
+	}
+	fmt.Fprintln(os.Stderr, theme.danger.Sprint("❌ "+err.Error()))
+	switch {
+	case errors.Is(err, ErrNotFound) && strings.HasPrefix(err.Error(), "snippet"):
+		fmt.Fprintln(os.Stderr, "💡 Tip: Run 'grb list' to see available snippets")
+	case errors.Is(err, ErrNotFound) && strings.HasPrefix(err.Error(), "vault"):
+		fmt.Fprintln(os.Stderr, "💡 Tip: Run 'grb vault list' to see available vaults")
+	case errors.Is(err, ErrLocked):
+		fmt.Fprintln(os.Stderr, "💡 Tip: Another grb (TUI or daemon) is busy; try again in a moment")
+	case errors.Is(err, ErrSealed):
+		fmt.Fprintln(os.Stderr, "💡 Tip: Run 'grb unlock' with 'grb daemon' running, or set GRB_PASSPHRASE")
+	}
+}
+
+// notFound wraps ErrNotFound with the id or alias that was looked up.
+func notFound(idOrAlias string) error {
+	return fmt.Errorf("snippet %w for \"%s\"", ErrNotFound, idOrAlias)
+}
+
+// writeClipboard copies text, reporting a missing clipboard as
+// ErrClipboardUnavailable.
+func writeClipboard(text string) error {
+	if err := clipboard.WriteAll(text); err != nil {
+		return fmt.Errorf("%w: %v", ErrClipboardUnavailable, err)
+	}
+	return nil
+}
+
+// readClipboard is the reading counterpart of writeClipboard.
+func readClipboard() (string, error) {
+	text, err := clipboard.ReadAll()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrClipboardUnavailable, err)
+	}
+	return text, nil
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.etcd.io/bbolt"
+	"gopkg.in/yaml.v3"
+)
+
+// ------------------ EXPORT ------------------
+
+// 'grb export --format <format>' writes the vault in a form an editor or
+// expander loads as is. Aliases become triggers (snippets without one get
+// grb-<id>), tags naming a language become scopes or filetypes and other
+// tags group snippets, and {{name}} placeholders become ${1:name} tab
+// stops. Secret and archived snippets are left out.
+
+// language is how a language tag is named by each editor.
+type language struct {
+	vscode  string // VS Code language id
+	sublime string // Sublime Text scope
+	vim     string // Vim filetype, also used for markdown fences
+}
+
+var languages = map[string]language{
+	"bash":       {"shellscript", "source.shell.bash", "sh"},
+	"c":          {"c", "source.c", "c"},
+	"cpp":        {"cpp", "source.c++", "cpp"},
+	"css":        {"css", "source.css", "css"},
+	"docker":     {"dockerfile", "source.dockerfile", "dockerfile"},
+	"dockerfile": {"dockerfile", "source.dockerfile", "dockerfile"},
+	"go":         {"go", "source.go", "go"},
+	"html":       {"html", "text.html", "html"},
+	"java":       {"java", "source.java", "java"},
+	"javascript": {"javascript", "source.js", "javascript"},
+	"js":         {"javascript", "source.js", "javascript"},
+	"json":       {"json", "source.json", "json"},
+	"lua":        {"lua", "source.lua", "lua"},
+	"make":       {"makefile", "source.makefile", "make"},
+	"markdown":   {"markdown", "text.html.markdown", "markdown"},
+	"md":         {"markdown", "text.html.markdown", "markdown"},
+	"php":        {"php", "embedding.php", "php"},
+	"py":         {"python", "source.python", "python"},
+	"python":     {"python", "source.python", "python"},
+	"rb":         {"ruby", "source.ruby", "ruby"},
+	"ruby":       {"ruby", "source.ruby", "ruby"},
+	"rust":       {"rust", "source.rust", "rust"},
+	"sh":         {"shellscript", "source.shell", "sh"},
+	"shell":      {"shellscript", "source.shell", "sh"},
+	"sql":        {"sql", "source.sql", "sql"},
+	"ts":         {"typescript", "source.ts", "typescript"},
+	"typescript": {"typescript", "source.ts", "typescript"},
+	"yaml":       {"yaml", "source.yaml", "yaml"},
+	"zsh":        {"shellscript", "source.shell.bash", "zsh"},
+}
+
+// snippetLanguages returns the languages named by s's tags.
+func snippetLanguages(s snippet) []language {
+	var langs []language
+	for _, t := range splitTags(s.tag) {
+		if l, ok := languages[strings.ToLower(t)]; ok {
+			langs = append(langs, l)
+		}
+	}
+	return langs
+}
+
+// trigger is what the editor expands: the alias, or grb-<id>.
+func trigger(s snippet) string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return "grb-" + s.id
+}
+
+// describe is a one-line description: the tags, or the first line.
+func describe(s snippet) string {
+	if s.tag != "" {
+		return "grb: " + s.tag
+	}
+	return "grb: " + truncate(oneLine(s.text), 40)
+}
+
+// toTabStops turns {{name}} placeholders into ${1:name} tab stops,
+// numbered by first appearance; a repeated name mirrors the first one.
+// {{name:default}} becomes ${1:default}. escape is applied to the text
+// around them.
+func toTabStops(text string, escape func(string) string) string {
+	var out strings.Builder
+	stops := map[string]int{}
+	last := 0
+	for _, m := range placeholderRe.FindAllStringSubmatchIndex(text, -1) {
+		out.WriteString(escape(text[last:m[0]]))
+		last = m[1]
+		name := text[m[2]:m[3]]
+		if n, ok := stops[name]; ok {
+			fmt.Fprintf(&out, "$%d", n)
+			continue
+		}
+		n := len(stops) + 1
+		stops[name] = n
+		value := name
+		if m[4] >= 0 {
+			value = text[m[4]:m[5]]
+		}
+		value = strings.NewReplacer(`\`, `\\`, `$`, `\$`, `}`, `\}`).Replace(value)
+		fmt.Fprintf(&out, "${%d:%s}", n, value)
+	}
+	out.WriteString(escape(text[last:]))
+	return out.String()
+}
+
+// escapeTextMate escapes text for VS Code and Sublime snippet bodies.
+var escapeTextMate = strings.NewReplacer(`\`, `\\`, `$`, `\$`).Replace
+
+// escapeUltiSnips escapes text for UltiSnips bodies.
+var escapeUltiSnips = strings.NewReplacer(`\`, `\\`, `$`, `\$`, "`", "\\`").Replace
+
+type exporter struct {
+	out   string // default output, a file or (dir) a directory
+	dir   bool
+	write func(out string, snippets []snippet) error
+}
+
+var exporters = map[string]exporter{
+	"vscode":        {"grb.code-snippets", false, exportVSCode},
+	"sublime":       {"grb-sublime", true, exportSublime},
+	"vim-ultisnips": {"UltiSnips", true, exportUltiSnips},
+	"espanso":       {"grb.yml", false, exportEspanso},
+	"markdown":      {"grb-snippets.md", false, exportMarkdown},
+}
+
+func exportFormats() []string {
+	names := make([]string, 0, len(exporters))
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeOut writes data to path, or to stdout for "-".
+func writeOut(path string, data []byte) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// exportVSCode writes a .code-snippets file; language tags become the
+// scope.
+func exportVSCode(out string, snippets []snippet) error {
+	type vscodeSnippet struct {
+		Prefix      string   `json:"prefix"`
+		Body        []string `json:"body"`
+		Description string   `json:"description"`
+		Scope       string   `json:"scope,omitempty"`
+	}
+	file := map[string]vscodeSnippet{}
+	for _, s := range snippets {
+		var scopes []string
+		for _, l := range snippetLanguages(s) {
+			scopes = append(scopes, l.vscode)
+		}
+		body := toTabStops(strings.ReplaceAll(s.text, "\r\n", "\n"), escapeTextMate)
+		file[fmt.Sprintf("%s [%s]", trigger(s), s.id)] = vscodeSnippet{
+			Prefix:      trigger(s),
+			Body:        strings.Split(body, "\n"),
+			Description: describe(s),
+			Scope:       strings.Join(scopes, ","),
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(file); err != nil {
+		return err
+	}
+	return writeOut(out, buf.Bytes())
+}
+
+// exportSublime writes one .sublime-snippet per snippet into a directory,
+// for the Packages/User folder.
+func exportSublime(out string, snippets []snippet) error {
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+	for _, s := range snippets {
+		var scopes []string
+		for _, l := range snippetLanguages(s) {
+			scopes = append(scopes, l.sublime)
+		}
+		body := toTabStops(s.text, escapeTextMate)
+		var b strings.Builder
+		b.WriteString("<snippet>\n")
+		b.WriteString("\t<content><![CDATA[" + strings.ReplaceAll(body, "]]>", "]]]]><![CDATA[>") + "]]></content>\n")
+		b.WriteString("\t<tabTrigger>" + xmlEscape(trigger(s)) + "</tabTrigger>\n")
+		if len(scopes) > 0 {
+			b.WriteString("\t<scope>" + strings.Join(scopes, ", ") + "</scope>\n")
+		}
+		b.WriteString("\t<description>" + xmlEscape(describe(s)) + "</description>\n")
+		b.WriteString("</snippet>\n")
+		name := filepath.Join(out, "grb-"+s.id+".sublime-snippet")
+		if err := os.WriteFile(name, []byte(b.String()), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var xmlEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace
+
+// exportUltiSnips writes <filetype>.snippets files into a directory, for
+// ~/.vim/UltiSnips. Snippets without a language tag go to all.snippets.
+func exportUltiSnips(out string, snippets []snippet) error {
+	files := map[string]*strings.Builder{}
+	for _, s := range snippets {
+		types := []string{"all"}
+		if langs := snippetLanguages(s); len(langs) > 0 {
+			types = nil
+			for _, l := range langs {
+				types = append(types, l.vim)
+			}
+		}
+		body := toTabStops(strings.ReplaceAll(s.text, "\r\n", "\n"), escapeUltiSnips)
+		for _, ft := range types {
+			b, ok := files[ft]
+			if !ok {
+				b = &strings.Builder{}
+				b.WriteString("# Exported from grb\n")
+				files[ft] = b
+			}
+			desc := strings.ReplaceAll(describe(s), `"`, "'")
+			fmt.Fprintf(b, "\nsnippet %s \"%s\"\n%s\nendsnippet\n", trigger(s), desc, body)
+		}
+	}
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+	for ft, b := range files {
+		if err := os.WriteFile(filepath.Join(out, ft+".snippets"), []byte(b.String()), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportEspanso writes an Espanso match file, grouped by tag. Snippets
+// with placeholders become forms.
+func exportEspanso(out string, snippets []snippet) error {
+	type field struct {
+		Default string `yaml:"default"`
+	}
+	type match struct {
+		Trigger    string           `yaml:"trigger"`
+		Replace    string           `yaml:"replace,omitempty"`
+		Form       string           `yaml:"form,omitempty"`
+		FormFields map[string]field `yaml:"form_fields,omitempty"`
+		Label      string           `yaml:"label,omitempty"`
+	}
+
+	var b strings.Builder
+	b.WriteString("# Exported from grb\nmatches:\n")
+	group := "\x00"
+	for _, s := range groupByTag(snippets) {
+		if t := firstTag(s); t != group {
+			group = t
+			if group == "" {
+				b.WriteString("  # untagged\n")
+			} else {
+				fmt.Fprintf(&b, "  # %s\n", group)
+			}
+		}
+		m := match{Trigger: ":" + trigger(s), Label: describe(s)}
+		if names := placeholders(s.text); len(names) > 0 {
+			fields := map[string]field{}
+			m.Form = placeholderRe.ReplaceAllStringFunc(s.text, func(p string) string {
+				sub := placeholderRe.FindStringSubmatch(p)
+				if strings.Contains(p, ":") {
+					fields[sub[1]] = field{Default: sub[2]}
+				}
+				return "[[" + sub[1] + "]]"
+			})
+			if len(fields) > 0 {
+				m.FormFields = fields
+			}
+		} else {
+			m.Replace = s.text
+		}
+		var data bytes.Buffer
+		enc := yaml.NewEncoder(&data)
+		enc.SetIndent(2)
+		if err := enc.Encode([]match{m}); err != nil {
+			return err
+		}
+		for _, line := range strings.SplitAfter(strings.TrimRight(data.String(), "\n"), "\n") {
+			b.WriteString("  " + line)
+		}
+		b.WriteString("\n")
+	}
+	return writeOut(out, []byte(b.String()))
+}
+
+// exportMarkdown writes a cheat sheet with one section per tag.
+func exportMarkdown(out string, snippets []snippet) error {
+	var b strings.Builder
+	b.WriteString("# grb snippets\n")
+	group := "\x00"
+	for _, s := range groupByTag(snippets) {
+		if t := firstTag(s); t != group {
+			group = t
+			if group == "" {
+				b.WriteString("\n## Untagged\n")
+			} else {
+				fmt.Fprintf(&b, "\n## %s\n", group)
+			}
+		}
+		title := "Snippet " + s.id
+		if s.alias != "" {
+			title = "`" + s.alias + "`"
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", title)
+		if s.tag != "" {
+			fmt.Fprintf(&b, "Tags: %s\n\n", s.tag)
+		}
+		lang := ""
+		if langs := snippetLanguages(s); len(langs) > 0 {
+			lang = langs[0].vim
+		}
+		fence := "```"
+		for strings.Contains(s.text, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&b, "%s%s\n%s\n%s\n", fence, lang, strings.TrimRight(s.text, "\n"), fence)
+	}
+	return writeOut(out, []byte(b.String()))
+}
+
+func firstTag(s snippet) string {
+	if tags := splitTags(s.tag); len(tags) > 0 {
+		return tags[0]
+	}
+	return ""
+}
+
+// groupByTag orders snippets by first tag, untagged last, then by id.
+func groupByTag(snippets []snippet) []snippet {
+	sorted := append([]snippet(nil), snippets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := firstTag(sorted[i]), firstTag(sorted[j])
+		if a != b {
+			return b == "" || a != "" && a < b
+		}
+		return lessID(sorted[i].id, sorted[j].id)
+	})
+	return sorted
+}
+
+// exportSnippets writes the vault's snippets (those with tag, if set) in
+// format to out, or to the format's default file.
+func exportSnippets(format, out, tag string) error {
+	exp, ok := exporters[format]
+	if !ok {
+		return usagef("unknown format %q (use %s)", format, strings.Join(exportFormats(), ", "))
+	}
+	if out == "" {
+		out = exp.out
+	}
+	if out == "-" && exp.dir {
+		return usagef("%s writes a directory; use --out <dir>", format)
+	}
+	out = expandHome(out)
+
+	var snippets []snippet
+	skipped := 0
+	err := db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(snippetsKey(vault)).ForEach(func(k, v []byte) error {
+			s := parseSnippet(k, v)
+			switch {
+			case s.archived, tag != "" && !hasTag(s.tag, tag):
+			case s.secret:
+				skipped++
+			default:
+				snippets = append(snippets, s)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(snippets, func(i, j int) bool { return lessID(snippets[i].id, snippets[j].id) })
+	if len(snippets) == 0 {
+		say(theme.highlight, "⚠ No snippets to export.")
+		return nil
+	}
+	if err := exp.write(out, snippets); err != nil {
+		return err
+	}
+	if out == "-" {
+		return nil
+	}
+
+	say(theme.success, "📤 Exported %d snippet(s) to %s (%s)", len(snippets), out, format)
+	if skipped > 0 {
+		fmt.Printf("%s Left out %d secret snippet(s)\n", theme.highlight.Sprint("⚠"), skipped)
+	}
+	tips := map[string]string{
+		"vscode":        "Copy it to your VS Code user snippets folder (Snippets: Configure User Snippets)",
+		"sublime":       "Copy the folder into Sublime Text's Packages/User",
+		"vim-ultisnips": "Copy the files into ~/.vim/UltiSnips (or ~/.config/nvim/UltiSnips)",
+		"espanso":       "Copy it into Espanso's match/ folder",
+		"markdown":      "Open it in any Markdown viewer",
+	}
+	fmt.Println("💡 Tip: " + tips[format])
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToTabStops(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"ssh {{host}}", "ssh ${1:host}"},
+		{"scp {{file}} {{host}}:{{file}}", "scp ${1:file} ${2:host}:$1"},
+		{"curl {{url:http://localhost}}", "curl ${1:http://localhost}"},
+		{"{{ name }} and {{name}}", "${1:name} and $1"},
+		{"echo $HOME {{x:a$b}}", `echo \$HOME ${1:a\$b}`},
+		{`C:\tmp {{d:C:\dir}}`, `C:\\tmp ${1:C:\\dir}`},
+		{"no placeholders", "no placeholders"},
+	}
+	for _, tt := range tests {
+		if got := toTabStops(tt.in, escapeTextMate); got != tt.want {
+			t.Errorf("toTabStops(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got, want := toTabStops("echo `date` {{x}}", escapeUltiSnips), "echo \\`date\\` ${1:x}"; got != want {
+		t.Errorf("toTabStops for UltiSnips = %q, want %q", got, want)
+	}
+}
+
+// exportSet is what the writer tests export; exportSnippets keeps secret
+// and archived snippets from them (TestExportSkipsSecrets).
+var exportSet = []snippet{
+	{id: "1", alias: "ssh", tag: "net", text: "ssh {{user}}@{{host}} # as {{user}}"},
+	{id: "2", alias: "home", tag: "shell", text: "echo $HOME \\n\nls"},
+	{id: "3", tag: "go", text: "fmt.Println({{msg}})"},
+	{id: "4", alias: "sig", text: "Best,\nAnn"},
+}
+
+func TestVSCodeRoundTrip(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "grb.code-snippets")
+	if err := exportVSCode(out, exportSet); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseVSCode(out, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []imported{
+		{text: "fmt.Println({{msg}})", tag: "go", alias: "grb-3"},
+		{text: "echo $HOME \\n\nls", tag: "shellscript", alias: "home"},
+		{text: "Best,\nAnn", alias: "sig"},
+		{text: "ssh {{user}}@{{host}} # as {{user}}", alias: "ssh"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %#v\nwant %#v", got, want)
+	}
+}
+
+func TestEspansoRoundTrip(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "grb.yml")
+	if err := exportEspanso(out, exportSet); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseEspanso(out, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{}
+	for _, s := range exportSet {
+		want[trigger(s)] = s.text
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d matches, want %d: %#v", len(got), len(want), got)
+	}
+	for _, m := range got {
+		if want[m.alias] != m.text {
+			t.Errorf("%s: text = %q, want %q", m.alias, m.text, want[m.alias])
+		}
+	}
+}
+
+func TestEspansoFormDefaults(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "grb.yml")
+	if err := exportEspanso(out, []snippet{{id: "1", alias: "db", text: "psql -p {{port:5432}} {{name}}"}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"form: psql -p [[port]] [[name]]", "port:", "default: \"5432\""} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("output lacks %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestExportSublime(t *testing.T) {
+	dir := t.TempDir()
+	snippets := []snippet{{id: "7", alias: "end", tag: "go", text: "a]]>b {{x}} <&>"}}
+	if err := exportSublime(dir, snippets); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "grb-7.sublime-snippet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<content><![CDATA[a]]]]><![CDATA[>b ${1:x} <&>]]></content>",
+		"<tabTrigger>end</tabTrigger>",
+		"<scope>source.go</scope>",
+		"<description>grb: go</description>",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("output lacks %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestExportUltiSnips(t *testing.T) {
+	dir := t.TempDir()
+	if err := exportUltiSnips(dir, exportSet); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"all.snippets": "\nsnippet sig \"grb: Best, ⏎ Ann\"\nBest,\nAnn\nendsnippet\n",
+		"go.snippets":  "\nsnippet grb-3 \"grb: go\"\nfmt.Println(${1:msg})\nendsnippet\n",
+		"sh.snippets":  "\nsnippet home \"grb: shell\"\necho \\$HOME \\\\n\nls\nendsnippet\n",
+	}
+	for name, want := range files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(data), "# Exported from grb\n") || !strings.Contains(string(data), want) {
+			t.Errorf("%s lacks %q:\n%s", name, want, data)
+		}
+	}
+	// "net" is not a language, so ssh goes to all.snippets too.
+	data, _ := os.ReadFile(filepath.Join(dir, "all.snippets"))
+	if !strings.Contains(string(data), "snippet ssh \"grb: net\"\nssh ${1:user}@${2:host} # as $1\n") {
+		t.Errorf("all.snippets lacks ssh:\n%s", data)
+	}
+}
+
+func TestExportMarkdown(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "grb.md")
+	snippets := append([]snippet{{id: "5", tag: "md", text: "```go\nx\n```\n"}}, exportSet...)
+	if err := exportMarkdown(out, snippets); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"## go\n\n### Snippet 3\n\nTags: go\n\n```go\nfmt.Println({{msg}})\n```\n",
+		"### Snippet 5\n\nTags: md\n\n````markdown\n```go\nx\n```\n````\n",
+		"## Untagged\n\n### `sig`\n\n```\nBest,\nAnn\n```\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("output lacks %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestExportSkipsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	useDB(t, filepath.Join(dir, "grb.db"))
+	for i, secret := range []bool{false, true, false} {
+		if _, err := createSnippet(fmt.Sprintf("echo %d", i), "", fmt.Sprintf("s%d", i), secret); err != nil {
+			t.Fatal(err)
+		}
+	}
+	editStored(t, "s2", func(s *snippet) { s.archived = true })
+
+	out := filepath.Join(dir, "grb.code-snippets")
+	if err := exportSnippets("vscode", out, ""); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseVSCode(out, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []imported{{text: "echo 0", alias: "s0"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want only the plain snippet %#v", got, want)
+	}
+}
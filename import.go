@@ -0,0 +1,570 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.etcd.io/bbolt"
+	"gopkg.in/yaml.v3"
+)
+
+// ------------------ IMPORT ------------------
+
+// 'grb import --from <format> <path>' reads another tool's snippet files,
+// shows what would be created and, once confirmed, saves them in one
+// transaction. Trigger names become aliases and scopes, sections and file
+// names become tags. Tab stops and the tools' own placeholder syntaxes are
+// turned into {{name}} placeholders. A path may be a single file or a
+// directory, which is searched for the format's files. Snippets whose text
+// is already in the vault are skipped, and aliases that are invalid or
+// taken are dropped.
+
+// imported is one snippet read from another tool.
+type imported struct {
+	text  string
+	tag   string
+	alias string
+	note  string // why the alias was dropped, for the preview
+}
+
+type importer struct {
+	exts  []string // file extensions searched in directories; nil means any
+	parse func(path string, data []byte) ([]imported, error)
+}
+
+var importers = map[string]importer{
+	"vscode":  {[]string{".json", ".code-snippets"}, parseVSCode},
+	"espanso": {[]string{".yml", ".yaml"}, parseEspanso},
+	"pet":     {[]string{".toml"}, parsePet},
+	"cheat":   {nil, parseCheat},
+	"navi":    {[]string{".cheat"}, parseNavi},
+	"csv":     {[]string{".csv"}, parseCSV},
+}
+
+func importFormats() []string {
+	names := make([]string, 0, len(importers))
+	for name := range importers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readImport parses path, or every matching file under it.
+func readImport(format, path string) ([]imported, error) {
+	imp, ok := importers[format]
+	if !ok {
+		return nil, usagef("unknown format %q (use %s)", format, strings.Join(importFormats(), ", "))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if strings.HasPrefix(d.Name(), ".") && p != path {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() && (imp.exts == nil || hasExt(p, imp.exts)) {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var all []imported
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		snippets, err := imp.parse(file, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		all = append(all, snippets...)
+	}
+	return all, nil
+}
+
+func hasExt(path string, exts []string) bool {
+	for _, ext := range exts {
+		if strings.EqualFold(filepath.Ext(path), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseName is the file name without directory and extension.
+func baseName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// toAlias turns a trigger or name into an alias: surrounding punctuation
+// is trimmed and whitespace becomes '-'.
+func toAlias(name string) string {
+	name = strings.Trim(strings.TrimSpace(name), ":;/\\!.,")
+	return strings.Join(strings.Fields(name), "-")
+}
+
+// ------------------ FORMATS ------------------
+
+var (
+	tabStopRe  = regexp.MustCompile(`\$\{(\d+):((?:\\.|[^{}\\])*)\}|\$\{(\d+)\|([^|}]*)[^}]*\}|\$\{(\d+)\}|\$(\d+)|\\([\\$}])`)
+	nameRe     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	angleVarRe = regexp.MustCompile(`<([A-Za-z_][A-Za-z0-9_.-]*)(?:=([^<>]*))?>`)
+	formVarRe  = regexp.MustCompile(`\[\[\s*([A-Za-z0-9_.-]+)\s*\]\]`)
+)
+
+// espansoCursor marks where Espanso leaves the cursor.
+const espansoCursor = "$|$"
+
+// fromTabStops turns VS Code/TextMate tab stops into placeholders:
+// ${1:host} becomes {{host}}, ${2:two words} {{2:two words}}, $1 {{1}}
+// and the final $0 is dropped. A later $1 mirrors ${1:host} as {{host}},
+// and \$, \} and \\ are unescaped.
+func fromTabStops(text string) string {
+	names := map[string]string{}
+	return tabStopRe.ReplaceAllStringFunc(text, func(match string) string {
+		m := tabStopRe.FindStringSubmatch(match)
+		if m[7] != "" {
+			return m[7]
+		}
+		n, value := m[1]+m[3]+m[5]+m[6], unescapeTabStop(m[2]+m[4])
+		switch {
+		case n == "0":
+			return value
+		case value == "" && names[n] != "":
+			return "{{" + names[n] + "}}"
+		case value == "":
+			return "{{" + n + "}}"
+		case nameRe.MatchString(value):
+			names[n] = value
+			return "{{" + value + "}}"
+		}
+		return "{{" + n + ":" + value + "}}"
+	})
+}
+
+var unescapeTabStop = strings.NewReplacer(`\\`, `\`, `\$`, `$`, `\}`, `}`).Replace
+
+// fromAngleVars turns pet and navi's <name> and <name=default> into
+// placeholders.
+func fromAngleVars(text string) string {
+	return angleVarRe.ReplaceAllStringFunc(text, func(match string) string {
+		m := angleVarRe.FindStringSubmatch(match)
+		if strings.Contains(match, "=") {
+			return "{{" + m[1] + ":" + m[2] + "}}"
+		}
+		return "{{" + m[1] + "}}"
+	})
+}
+
+// stringList reads a JSON value that is either a string or a list of
+// strings.
+func stringList(raw json.RawMessage) []string {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return []string{one}
+	}
+	var many []string
+	json.Unmarshal(raw, &many)
+	return many
+}
+
+// stripJSONComments removes // and /* */ comments and trailing commas,
+// which VS Code allows in snippet files.
+func stripJSONComments(data []byte) []byte {
+	var out bytes.Buffer
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+		case c == ',':
+			rest := bytes.TrimLeft(data[i+1:], " \t\r\n")
+			if len(rest) == 0 || rest[0] != '}' && rest[0] != ']' {
+				out.WriteByte(c)
+			}
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+// parseVSCode reads a VS Code snippet file. The prefix is the alias and
+// the scope (or the language a <lang>.json file is for) the tag.
+func parseVSCode(path string, data []byte) ([]imported, error) {
+	var file map[string]struct {
+		Prefix json.RawMessage `json:"prefix"`
+		Body   json.RawMessage `json:"body"`
+		Scope  string          `json:"scope"`
+	}
+	if err := json.Unmarshal(stripJSONComments(data), &file); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(file))
+	for name := range file {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []imported
+	for _, name := range names {
+		s := file[name]
+		body := stringList(s.Body)
+		if len(body) == 0 {
+			continue
+		}
+		tag := s.Scope
+		if tag == "" && strings.EqualFold(filepath.Ext(path), ".json") {
+			tag = baseName(path)
+		}
+		alias := name
+		if prefixes := stringList(s.Prefix); len(prefixes) > 0 && prefixes[0] != "" {
+			alias = prefixes[0]
+		}
+		out = append(out, imported{
+			text:  fromTabStops(strings.Join(body, "\n")),
+			tag:   strings.Join(splitTags(tag), ","),
+			alias: toAlias(alias),
+		})
+	}
+	return out, nil
+}
+
+// parseEspanso reads an Espanso match file. The trigger is the alias and
+// the file name the tag; [[var]] form fields become placeholders.
+func parseEspanso(path string, data []byte) ([]imported, error) {
+	var file struct {
+		Matches []struct {
+			Trigger  string   `yaml:"trigger"`
+			Triggers []string `yaml:"triggers"`
+			Regex    string   `yaml:"regex"`
+			Replace  string   `yaml:"replace"`
+			Form     string   `yaml:"form"`
+		} `yaml:"matches"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	var out []imported
+	for _, m := range file.Matches {
+		text := m.Replace
+		if text == "" {
+			text = formVarRe.ReplaceAllString(m.Form, "{{$1}}")
+		}
+		if text == "" || m.Regex != "" {
+			continue // regex and image matches can't be snippets
+		}
+		trigger := m.Trigger
+		if trigger == "" && len(m.Triggers) > 0 {
+			trigger = m.Triggers[0]
+		}
+		out = append(out, imported{
+			text:  strings.ReplaceAll(text, espansoCursor, ""),
+			tag:   baseName(path),
+			alias: toAlias(trigger),
+		})
+	}
+	return out, nil
+}
+
+// parsePet reads pet's snippet.toml. pet has no names, so only tags are
+// kept.
+func parsePet(path string, data []byte) ([]imported, error) {
+	var file struct {
+		Snippets []struct {
+			Command string   `toml:"command"`
+			Tag     []string `toml:"tag"`
+		} `toml:"snippets"`
+	}
+	if _, err := toml.Decode(string(data), &file); err != nil {
+		return nil, err
+	}
+	var out []imported
+	for _, s := range file.Snippets {
+		if s.Command == "" {
+			continue
+		}
+		out = append(out, imported{text: fromAngleVars(s.Command), tag: strings.Join(s.Tag, ",")})
+	}
+	return out, nil
+}
+
+// parseCheat reads a cheat sheet: blocks separated by blank lines, each a
+// few # comment lines and the command. The sheet name and the tags in its
+// front matter become tags.
+func parseCheat(path string, data []byte) ([]imported, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	tag := baseName(path)
+	if rest, ok := strings.CutPrefix(text, "---\n"); ok {
+		front, body, found := strings.Cut(rest, "\n---\n")
+		if !found {
+			return nil, errors.New("front matter is not closed with ---")
+		}
+		var meta struct {
+			Tags []string `yaml:"tags"`
+		}
+		if err := yaml.Unmarshal([]byte(front), &meta); err != nil {
+			return nil, err
+		}
+		for _, t := range meta.Tags {
+			tag = addTag(tag, strings.TrimSpace(t))
+		}
+		text = body
+	}
+
+	var out []imported
+	for _, block := range strings.Split(text, "\n\n") {
+		var lines []string
+		for _, line := range strings.Split(block, "\n") {
+			if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			out = append(out, imported{text: strings.Join(lines, "\n"), tag: tag})
+		}
+	}
+	return out, nil
+}
+
+// parseNavi reads a navi .cheat file: '% tags' lines start a section,
+// '# description' lines describe the command that follows, '$ var: ...'
+// lines define variables and <var> marks a placeholder.
+func parseNavi(path string, data []byte) ([]imported, error) {
+	var out []imported
+	var tag string
+	var command []string
+	flush := func() {
+		if len(command) > 0 {
+			out = append(out, imported{text: fromAngleVars(strings.Join(command, "\n")), tag: tag})
+		}
+		command = nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "%"):
+			flush()
+			tag = strings.Join(splitTags(strings.TrimPrefix(trimmed, "%")), ",")
+		case trimmed == "", strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, "$"),
+			strings.HasPrefix(trimmed, ";"), strings.HasPrefix(trimmed, "@"):
+			flush()
+		default:
+			command = append(command, line)
+		}
+	}
+	flush()
+	return out, scanner.Err()
+}
+
+// parseCSV reads text,tag,alias rows. A header row naming the columns
+// (text, tag or tags, alias) may put them in any order.
+func parseCSV(path string, data []byte) ([]imported, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	col := map[string]int{"text": 0, "tag": 1, "alias": 2}
+	if len(rows) > 0 {
+		header := map[string]int{}
+		for i, name := range rows[0] {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "tags" {
+				name = "tag"
+			}
+			header[name] = i
+		}
+		if _, ok := header["text"]; ok {
+			col = map[string]int{"text": header["text"], "tag": -1, "alias": -1}
+			for _, name := range []string{"tag", "alias"} {
+				if i, ok := header[name]; ok {
+					col[name] = i
+				}
+			}
+			rows = rows[1:]
+		}
+	}
+	get := func(row []string, name string) string {
+		if i := col[name]; i >= 0 && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var out []imported
+	for _, row := range rows {
+		text := ""
+		if i := col["text"]; i < len(row) {
+			text = row[i]
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		out = append(out, imported{text: text, tag: get(row, "tag"), alias: get(row, "alias")})
+	}
+	return out, nil
+}
+
+// ------------------ IMPORT COMMAND ------------------
+
+// importSnippets previews what reading path as format would create and,
+// once confirmed, creates it.
+func importSnippets(format, path string, yes, dryRun bool) error {
+	path = expandHome(path)
+	found, err := readImport(format, path)
+	if err != nil {
+		return err
+	}
+
+	// Drop duplicates of what's in the vault (or earlier in the import),
+	// and aliases that can't be used.
+	var create []imported
+	skipped := 0
+	err = db.View(func(tx *bbolt.Tx) error {
+		texts := map[string]bool{}
+		tx.Bucket(snippetsKey(vault)).ForEach(func(k, v []byte) error {
+			texts[parseSnippet(k, v).text] = true
+			return nil
+		})
+		idx := tx.Bucket(aliasesKey(vault))
+		aliases := map[string]bool{}
+		for _, s := range found {
+			s.text = strings.TrimRight(s.text, "\r\n")
+			if strings.TrimSpace(s.text) == "" || texts[s.text] {
+				skipped++
+				continue
+			}
+			texts[s.text] = true
+			if s.alias != "" {
+				switch {
+				case validateAlias(s.alias) != nil:
+					s.note = fmt.Sprintf("alias %q is not valid in grb", s.alias)
+					s.alias = ""
+				case aliases[s.alias] || (idx != nil && idx.Get([]byte(s.alias)) != nil):
+					s.note = fmt.Sprintf("alias %q is already taken", s.alias)
+					s.alias = ""
+				default:
+					aliases[s.alias] = true
+				}
+			}
+			create = append(create, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Printf("%s %s (%s)\n", accent("📥 Import from"), path, format)
+	fmt.Println("─────────────────────────────────────────────")
+	if len(create) == 0 {
+		say(theme.highlight, "⚠ Nothing to import (%d found, %d already in grb)", len(found), skipped)
+		return nil
+	}
+	rows := make([][]string, len(create))
+	for i, s := range create {
+		rows[i] = []string{accent("+"), s.text, label(orDash(s.tag)), highlight(orDash(s.alias))}
+	}
+	printSnippetTable(rows)
+	for _, s := range create {
+		if s.note != "" {
+			fmt.Printf("%s %s, importing without it\n", highlight("⚠"), s.note)
+		}
+	}
+	if skipped > 0 {
+		fmt.Printf("%s Skipping %d snippet(s) already in grb\n", highlight("⚠"), skipped)
+	}
+	if dryRun {
+		fmt.Printf("💡 Tip: Run without --dry-run to create these %d snippet(s)\n", len(create))
+		return nil
+	}
+	if !yes {
+		fmt.Fprintf(os.Stderr, "%s Create %d snippet(s) in vault %s? [y/N] ", highlight("⚠"), len(create), vault)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("aborted")
+		}
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(vault))
+		for _, in := range create {
+			seq, _ := b.NextSequence()
+			id := fmt.Sprintf("%d", seq)
+			if err := claimAlias(tx, vault, in.alias, id); err != nil {
+				return err
+			}
+			s := snippet{text: in.text, tag: in.tag, alias: in.alias, created: time.Now().Unix()}
+			if err := putSnippet(b, id, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	say(theme.success, "📥 Imported %d snippet(s)", len(create))
+	fmt.Println("💡 Tip: Run 'grb list' to see them")
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStripJSONComments(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"line comment", "{\"a\": 1 // one\n}", "{\"a\": 1 \n}"},
+		{"block comment", `{/* x */"a": 1}`, `{"a": 1}`},
+		{"unclosed block comment", `{"a": 1} /* x`, `{"a": 1} `},
+		{"comment markers in a string", `{"url": "http://x/*y*/"}`, `{"url": "http://x/*y*/"}`},
+		{"escaped quote in a string", `{"a": "say \"//no\""}`, `{"a": "say \"//no\""}`},
+		{"trailing commas", "{\"a\": [1, 2,\n],\n}", "{\"a\": [1, 2\n]\n}"},
+		{"comma in a string", `{"a": ",}"}`, `{"a": ",}"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(stripJSONComments([]byte(tt.in))); got != tt.want {
+				t.Errorf("stripJSONComments(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromTabStops(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"ssh ${1:host}", "ssh {{host}}"},
+		{"echo ${2:two words}", "echo {{2:two words}}"},
+		{"cd $1 && ls${0}", "cd {{1}} && ls"},
+		{"${1|yes,no|}", "{{1:yes,no}}"},
+		{"${1|prod|}", "{{prod}}"},
+		{"git ${1}$0", "git {{1}}"},
+		{"scp ${1:file} ${2:host}:$1", "scp {{file}} {{host}}:{{file}}"},
+		{`echo \$HOME \\n \$1 ${1:a\}b}`, `echo $HOME \n $1 {{1:a}b}}`},
+	}
+	for _, tt := range tests {
+		if got := fromTabStops(tt.in); got != tt.want {
+			t.Errorf("fromTabStops(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFromAngleVars(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"ssh <host>", "ssh {{host}}"},
+		{"curl <url=http://localhost>", "curl {{url:http://localhost}}"},
+		{"a < b > c", "a < b > c"},
+	}
+	for _, tt := range tests {
+		if got := fromAngleVars(tt.in); got != tt.want {
+			t.Errorf("fromAngleVars(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParsers(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string, []byte) ([]imported, error)
+		path  string
+		data  string
+		want  []imported
+	}{
+		{
+			"vscode", parseVSCode, "go.json", `{
+				// a comment
+				"Print": {"prefix": ["pf", "print"], "body": ["fmt.Println(${1:msg})", "$0"],},
+				"Scoped": {"prefix": "sc", "body": "x", "scope": "go,sql"},
+				"Empty": {"prefix": "e", "body": []},
+			}`,
+			[]imported{
+				{text: "fmt.Println({{msg}})\n", tag: "go", alias: "pf"},
+				{text: "x", tag: "go,sql", alias: "sc"},
+			},
+		},
+		{
+			"vscode without prefix", parseVSCode, "my.code-snippets", `{"Deploy app": {"body": "make deploy"}}`,
+			[]imported{{text: "make deploy", alias: "Deploy-app"}},
+		},
+		{
+			"espanso", parseEspanso, "base.yml", `
+matches:
+  - trigger: ":sig"
+    replace: "Best, $|$Ann"
+  - triggers: [":a", ":b"]
+    replace: "ab"
+  - regex: ":d(\\d+)"
+    replace: "skipped"
+  - trigger: ":form"
+    form: "Hi [[ name ]]"
+`,
+			[]imported{
+				{text: "Best, Ann", tag: "base", alias: "sig"},
+				{text: "ab", tag: "base", alias: "a"},
+				{text: "Hi {{name}}", tag: "base", alias: "form"},
+			},
+		},
+		{
+			"pet", parsePet, "snippet.toml", `
+[[snippets]]
+  description = "ssh"
+  command = "ssh <user=root>@<host>"
+  tag = ["net", "ssh"]
+
+[[snippets]]
+  command = ""
+`,
+			[]imported{{text: "ssh {{user:root}}@{{host}}", tag: "net,ssh"}},
+		},
+		{
+			"cheat with front matter", parseCheat, "/sheets/tar", "---\ntags: [ archive ]\n---\n# extract\ntar xf file.tar\n\n# list\n# verbose\ntar tvf file.tar\n",
+			[]imported{
+				{text: "tar xf file.tar", tag: "tar,archive"},
+				{text: "tar tvf file.tar", tag: "tar,archive"},
+			},
+		},
+		{
+			"cheat with CRLF and only comments", parseCheat, "git", "# nothing here\r\n\r\ngit status\r\n",
+			[]imported{{text: "git status", tag: "git"}},
+		},
+		{
+			"navi", parseNavi, "docker.cheat", `% docker, containers
+
+# list containers
+docker ps -a
+
+# run an image
+docker run \
+  <image>
+$ image: docker images --format '{{.Repository}}'
+
+; a comment
+@ base
+% k8s
+kubectl get <resource=pods>
+`,
+			[]imported{
+				{text: "docker ps -a", tag: "docker,containers"},
+				{text: "docker run \\\n  {{image}}", tag: "docker,containers"},
+				{text: "kubectl get {{resource:pods}}", tag: "k8s"},
+			},
+		},
+		{
+			"csv without header", parseCSV, "s.csv", "echo hi,shell,hi\n\"a, \"\"quoted\"\"\nline\",,q\n,skipped,\n",
+			[]imported{
+				{text: "echo hi", tag: "shell", alias: "hi"},
+				{text: "a, \"quoted\"\nline", alias: "q"},
+			},
+		},
+		{
+			"csv with header", parseCSV, "s.csv", "Alias,Tags,Text\nup,ops,docker compose up\ndown\n",
+			[]imported{{text: "docker compose up", tag: "ops", alias: "up"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse(tt.path, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %#v\nwant %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCheatUnclosedFrontMatter(t *testing.T) {
+	if _, err := parseCheat("x", []byte("---\ntags: [a]\ngit status\n")); err == nil {
+		t.Fatal("want an error for front matter without a closing ---")
+	}
+}
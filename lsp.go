@@ -0,0 +1,641 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"go.etcd.io/bbolt"
+)
+
+// ------------------ JSON-RPC ------------------
+
+// grb lsp and grb rpc both speak JSON-RPC 2.0 over stdio. A request
+// without an id is a notification and gets no reply. Errors from grb
+// itself are reported as server errors carrying the exit code grb would
+// have used, so clients can tell "not found" from "locked".
+
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// toRPCError wraps a grb error for the wire.
+func toRPCError(err error) *rpcError {
+	var re *rpcError
+	if errors.As(err, &re) {
+		return re
+	}
+	code := exitCode(err)
+	if code == exitUsage {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return &rpcError{Code: rpcServerError, Message: err.Error(), Data: map[string]int{"exit_code": code}}
+}
+
+// rpcReply is the response to the request with id.
+func rpcReply(id json.RawMessage, result interface{}, err error) map[string]interface{} {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	reply := map[string]interface{}{"jsonrpc": "2.0", "id": id}
+	if err != nil {
+		reply["error"] = toRPCError(err)
+	} else {
+		reply["result"] = result
+	}
+	return reply
+}
+
+// decodeParams unmarshals a request's params into v.
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// ------------------ LSP ------------------
+
+// 'grb lsp' is a language server on stdin/stdout, so any editor with an
+// LSP client gets grb without a plugin:
+//   - completion offers every alias (pinned and most used first) and
+//     inserts the snippet with {{name}} placeholders as tab stops;
+//   - hover on an alias shows the whole snippet;
+//   - the "Save selection as grb snippet" code action saves the selected
+//     text, tagged with the document's language.
+// Aliases resolve in the current vault, then in the project's .grb.yaml
+// (looked up from the directory the editor starts grb in). Secret and
+// archived snippets are not offered. Inserting a completion counts as a
+// use. Messages are framed with Content-Length headers as LSP requires.
+
+const (
+	lspSaveSelection = "grb.saveSelection"
+	lspRecordUse     = "grb.recordUse"
+)
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"` // in UTF-16 code units
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDocumentID struct {
+	URI string `json:"uri"`
+}
+
+type lspDocument struct {
+	language string
+	text     string
+}
+
+type lspServer struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*lspDocument
+	shutdown bool
+}
+
+// readFramed reads one message and its Content-Length header.
+func readFramed(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("lsp: bad Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("lsp: message without Content-Length")
+	}
+	data := make([]byte, length)
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
+
+func (ls *lspServer) send(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(ls.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+// notify sends a notification to the editor.
+func (ls *lspServer) notify(method string, params interface{}) error {
+	return ls.send(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// showMessage pops up msg in the editor; kind is 1 error, 2 warning, 3 info.
+func (ls *lspServer) showMessage(kind int, msg string) error {
+	return ls.notify("window/showMessage", map[string]interface{}{"type": kind, "message": msg})
+}
+
+func (ls *lspServer) handle(req rpcRequest) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   map[string]interface{}{"openClose": true, "change": 1},
+				"completionProvider": map[string]interface{}{},
+				"hoverProvider":      true,
+				"codeActionProvider": map[string]interface{}{"codeActionKinds": []string{"refactor"}},
+				"executeCommandProvider": map[string]interface{}{
+					"commands": []string{lspSaveSelection, lspRecordUse},
+				},
+			},
+			"serverInfo": map[string]string{"name": "grb"},
+		}, nil
+	case "shutdown":
+		ls.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI        string `json:"uri"`
+				LanguageID string `json:"languageId"`
+				Text       string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		ls.docs[p.TextDocument.URI] = &lspDocument{p.TextDocument.LanguageID, p.TextDocument.Text}
+		return nil, nil
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument   lspDocumentID `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		// Full sync: the last change holds the whole document.
+		if doc := ls.docs[p.TextDocument.URI]; doc != nil && len(p.ContentChanges) > 0 {
+			doc.text = p.ContentChanges[len(p.ContentChanges)-1].Text
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p struct {
+			TextDocument lspDocumentID `json:"textDocument"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		delete(ls.docs, p.TextDocument.URI)
+		return nil, nil
+	case "textDocument/completion":
+		return ls.completion(req.Params)
+	case "textDocument/hover":
+		return ls.hover(req.Params)
+	case "textDocument/codeAction":
+		return ls.codeAction(req.Params)
+	case "workspace/executeCommand":
+		return ls.executeCommand(req.Params)
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not supported: " + req.Method}
+}
+
+// offsetAt converts an LSP position to a byte offset in text, clamped to
+// the end of the line.
+func offsetAt(text string, p lspPosition) int {
+	off := 0
+	for line := 0; line < p.Line; line++ {
+		i := strings.IndexByte(text[off:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		off += i + 1
+	}
+	for units := 0; off < len(text) && units < p.Character; {
+		r, size := utf8.DecodeRuneInString(text[off:])
+		if r == '\n' {
+			break
+		}
+		units += utf16.RuneLen(r)
+		off += size
+	}
+	return off
+}
+
+// utf16Len is the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// aliasRune reports whether r can be part of an alias in a document.
+// Aliases may hold any non-space character, but quotes and brackets
+// around one are taken as surrounding code.
+func aliasRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune("\"'`()[]{}<>,;", r)
+}
+
+// wordAt returns the byte range of the alias-like word around off.
+func wordAt(text string, off int) (int, int) {
+	start, end := off, off
+	for start > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:start])
+		if !aliasRune(r) {
+			break
+		}
+		start -= size
+	}
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !aliasRune(r) {
+			break
+		}
+		end += size
+	}
+	return start, end
+}
+
+// aliasedSnippets returns the snippets completion offers: the vault's
+// with an alias, then the project's whose alias the vault doesn't use.
+func aliasedSnippets() ([]snippet, error) {
+	var list []snippet
+	err := withDB(func() error {
+		return db.View(func(tx *bbolt.Tx) error {
+			return tx.Bucket(snippetsKey(vault)).ForEach(func(k, v []byte) error {
+				s := parseSnippet(k, v)
+				if s.alias != "" && !s.secret && !s.archived {
+					s.vault = vault
+					list = append(list, s)
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	taken := map[string]bool{}
+	for _, s := range list {
+		taken[s.alias] = true
+	}
+	for _, s := range projectSnippets() {
+		if s.alias != "" && !taken[s.alias] {
+			list = append(list, s)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.pinned != b.pinned {
+			return a.pinned
+		}
+		if a.useCount != b.useCount {
+			return a.useCount > b.useCount
+		}
+		return a.alias < b.alias
+	})
+	return list, nil
+}
+
+// lookupAlias finds the snippet an alias names, in the vault or the
+// project. Unlike findSnippet it ignores ids, so hovering over a number
+// shows nothing.
+func lookupAlias(alias string) (snippet, bool, error) {
+	var s snippet
+	found := false
+	err := withDB(func() error {
+		return db.View(func(tx *bbolt.Tx) error {
+			idx := tx.Bucket(aliasesKey(vault))
+			if idx == nil {
+				return nil
+			}
+			if id := idx.Get([]byte(alias)); id != nil {
+				s, found = findSnippet(tx, vault, string(id))
+			}
+			return nil
+		})
+	})
+	if err != nil || found {
+		return s, found, err
+	}
+	for _, ps := range projectSnippets() {
+		if ps.alias == alias {
+			return ps, true, nil
+		}
+	}
+	return snippet{}, false, nil
+}
+
+// fenced renders text as a markdown code block in the snippet's language.
+func fenced(s snippet, text string) string {
+	lang := ""
+	if langs := snippetLanguages(s); len(langs) > 0 {
+		lang = langs[0].vim
+	}
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fmt.Sprintf("%s%s\n%s\n%s", fence, lang, strings.TrimRight(text, "\n"), fence)
+}
+
+func (ls *lspServer) completion(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		TextDocument lspDocumentID `json:"textDocument"`
+		Position     lspPosition   `json:"position"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	snippets, err := aliasedSnippets()
+	if err != nil {
+		return nil, err
+	}
+
+	// Replace the alias typed so far, which may hold characters editors
+	// don't treat as part of a word.
+	start := p.Position
+	if doc := ls.docs[p.TextDocument.URI]; doc != nil {
+		off := offsetAt(doc.text, p.Position)
+		wordStart, _ := wordAt(doc.text, off)
+		start.Character -= utf16Len(doc.text[wordStart:off])
+	}
+	replace := lspRange{start, p.Position}
+
+	items := make([]map[string]interface{}, 0, len(snippets))
+	for i, s := range snippets {
+		detail := "grb"
+		if s.project {
+			detail = "grb project"
+		}
+		if s.tag != "" {
+			detail += " · " + strings.Join(splitTags(s.tag), ", ")
+		}
+		item := map[string]interface{}{
+			"label":            s.alias,
+			"kind":             15, // Snippet
+			"detail":           detail,
+			"documentation":    map[string]string{"kind": "markdown", "value": fenced(s, s.text)},
+			"filterText":       s.alias,
+			"sortText":         fmt.Sprintf("%05d", i),
+			"insertTextFormat": 2, // Snippet
+			"textEdit":         map[string]interface{}{"range": replace, "newText": toTabStops(s.text, escapeTextMate)},
+		}
+		if !s.project {
+			item["command"] = map[string]interface{}{
+				"title":     "Record use",
+				"command":   lspRecordUse,
+				"arguments": []string{s.vault, s.id},
+			}
+		}
+		items = append(items, item)
+	}
+	return map[string]interface{}{"isIncomplete": false, "items": items}, nil
+}
+
+func (ls *lspServer) hover(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		TextDocument lspDocumentID `json:"textDocument"`
+		Position     lspPosition   `json:"position"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	doc := ls.docs[p.TextDocument.URI]
+	if doc == nil {
+		return nil, nil
+	}
+	off := offsetAt(doc.text, p.Position)
+	start, end := wordAt(doc.text, off)
+	word := doc.text[start:end]
+	if word == "" {
+		return nil, nil
+	}
+	s, ok, err := lookupAlias(word)
+	if err == nil && !ok {
+		// "see gs." names gs, not "gs."
+		word = strings.TrimRight(word, ".:!?")
+		end = start + len(word)
+		s, ok, err = lookupAlias(word)
+	}
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	header := fmt.Sprintf("**grb** `%s` · [%s]", s.alias, s.id)
+	if s.pinned {
+		header += " · 📌"
+	}
+	if s.tag != "" {
+		header += " · 🏷 " + strings.Join(splitTags(s.tag), ", ")
+	}
+	if !s.project {
+		header += fmt.Sprintf(" · used %d time(s)", s.useCount)
+	}
+	lineStart := p.Position.Character - utf16Len(doc.text[start:off])
+	return map[string]interface{}{
+		"contents": map[string]string{"kind": "markdown", "value": header + "\n\n" + fenced(s, s.shownText())},
+		"range": lspRange{
+			lspPosition{p.Position.Line, lineStart},
+			lspPosition{p.Position.Line, lineStart + utf16Len(doc.text[start:end])},
+		},
+	}, nil
+}
+
+// selectionArgs is the argument of grb.saveSelection.
+type selectionArgs struct {
+	Text string `json:"text"`
+	Tag  string `json:"tag"`
+}
+
+func (ls *lspServer) codeAction(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		TextDocument lspDocumentID `json:"textDocument"`
+		Range        lspRange      `json:"range"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	actions := []interface{}{}
+	doc := ls.docs[p.TextDocument.URI]
+	if doc == nil {
+		return actions, nil
+	}
+	start, end := offsetAt(doc.text, p.Range.Start), offsetAt(doc.text, p.Range.End)
+	if start > end {
+		start, end = end, start
+	}
+	text := doc.text[start:end]
+	if strings.TrimSpace(text) == "" {
+		return actions, nil
+	}
+	title := "Save selection as grb snippet"
+	return append(actions, map[string]interface{}{
+		"title": title,
+		"kind":  "refactor",
+		"command": map[string]interface{}{
+			"title":     title,
+			"command":   lspSaveSelection,
+			"arguments": []selectionArgs{{Text: text, Tag: languageTag(doc.language)}},
+		},
+	}), nil
+}
+
+// languageTag is the grb tag for an LSP language id, or "" if it names no
+// language grb knows.
+func languageTag(id string) string {
+	if _, ok := languages[id]; ok {
+		return id
+	}
+	var tags []string
+	for tag, l := range languages {
+		if l.vscode == id {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	sort.Strings(tags)
+	return tags[0]
+}
+
+func (ls *lspServer) executeCommand(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Command   string            `json:"command"`
+		Arguments []json.RawMessage `json:"arguments"`
+	}
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	switch p.Command {
+	case lspSaveSelection:
+		var args selectionArgs
+		if len(p.Arguments) > 0 {
+			if err := decodeParams(p.Arguments[0], &args); err != nil {
+				return nil, err
+			}
+		}
+		if strings.TrimSpace(args.Text) == "" {
+			return nil, usagef("nothing selected to save")
+		}
+		var id string
+		err := withDB(func() error {
+			var err error
+			id, err = createSnippet(args.Text, args.Tag, "", false)
+			return err
+		})
+		if err != nil {
+			ls.showMessage(1, "grb: "+err.Error())
+			return nil, err
+		}
+		return nil, ls.showMessage(3, fmt.Sprintf("Saved as grb snippet [%s]; give it an alias with 'grb alias %s <name>'", id, id))
+	case lspRecordUse:
+		var vaultName, id string
+		if len(p.Arguments) < 2 {
+			return nil, usagef("%s needs a vault and an id", lspRecordUse)
+		}
+		if err := decodeParams(p.Arguments[0], &vaultName); err != nil {
+			return nil, err
+		}
+		if err := decodeParams(p.Arguments[1], &id); err != nil {
+			return nil, err
+		}
+		return nil, withDB(func() error {
+			return db.Update(func(tx *bbolt.Tx) error {
+				if !vaultExists(tx, vaultName) {
+					return fmt.Errorf("vault %w: %s", ErrNotFound, vaultName)
+				}
+				s, ok := findSnippet(tx, vaultName, id)
+				if !ok {
+					return notFound(id)
+				}
+				s.useCount++
+				s.created = time.Now().Unix()
+				if err := logEvent(tx, eventInsert, vaultName, s.id); err != nil {
+					return err
+				}
+				return putSnippet(tx.Bucket(snippetsKey(vaultName)), s.id, s)
+			})
+		})
+	}
+	return nil, &rpcError{Code: rpcInvalidParams, Message: "unknown command: " + p.Command}
+}
+
+// runLSP serves LSP on in and out until the editor sends exit.
+func runLSP(in io.Reader, out io.Writer) error {
+	ls := &lspServer{in: bufio.NewReader(in), out: out, docs: map[string]*lspDocument{}}
+	releaseDB()
+	for {
+		data, err := readFramed(ls.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req rpcRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			ls.send(rpcReply(nil, nil, &rpcError{Code: rpcParseError, Message: err.Error()}))
+			continue
+		}
+		if req.Method == "exit" {
+			if !ls.shutdown {
+				return errors.New("lsp: exit before shutdown")
+			}
+			return nil
+		}
+		result, err := ls.handle(req)
+		if len(req.ID) == 0 {
+			continue // notification
+		}
+		if err := ls.send(rpcReply(req.ID, result, err)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lspDoc has characters of one, two and three UTF-8 bytes and one that
+// is two UTF-16 code units.
+const lspDoc = "héllo\n😀 日本 gs.\n"
+
+func TestOffsetAt(t *testing.T) {
+	tests := []struct {
+		line, char int
+		want       int
+	}{
+		{0, 0, 0},
+		{0, 2, 3},  // after "hé"
+		{0, 99, 6}, // clamped to the end of the line
+		{1, 0, 7},  // start of the second line
+		{1, 2, 11}, // after the emoji, two code units
+		{1, 1, 11}, // inside the emoji: it can't be split
+		{1, 5, 18}, // after "日本"
+		{1, 8, 21}, // after "gs"
+		{2, 0, 23}, // the empty last line
+		{5, 0, 23}, // past the last line
+	}
+	for _, tt := range tests {
+		if got := offsetAt(lspDoc, lspPosition{tt.line, tt.char}); got != tt.want {
+			t.Errorf("offsetAt(%d:%d) = %d, want %d", tt.line, tt.char, got, tt.want)
+		}
+	}
+}
+
+func TestWordAt(t *testing.T) {
+	tests := []struct {
+		text string
+		off  int
+		want string
+	}{
+		{lspDoc, 19, "gs."},
+		{lspDoc, 21, "gs."},
+		{lspDoc, 15, "日本"},
+		{lspDoc, 7, "😀"},
+		{lspDoc, 3, "héllo"},
+		{`x("k8s-pods") `, 5, "k8s-pods"},
+		{"a  b", 2, ""},
+	}
+	for _, tt := range tests {
+		start, end := wordAt(tt.text, tt.off)
+		if got := tt.text[start:end]; got != tt.want {
+			t.Errorf("wordAt(%q, %d) = %q, want %q", tt.text, tt.off, got, tt.want)
+		}
+	}
+	if got := utf16Len("😀 日本"); got != 5 {
+		t.Errorf("utf16Len = %d, want 5", got)
+	}
+}
+
+// lspSession runs the server over msgs and returns its replies by id.
+func lspSession(t *testing.T, msgs ...map[string]interface{}) map[string]json.RawMessage {
+	t.Helper()
+	var in bytes.Buffer
+	for _, m := range msgs {
+		m["jsonrpc"] = "2.0"
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	}
+	var out bytes.Buffer
+	if err := runLSP(&in, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	replies := map[string]json.RawMessage{}
+	r := bufio.NewReader(&out)
+	for {
+		data, err := readFramed(r)
+		if err == io.EOF {
+			return replies
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reply struct {
+			ID     json.RawMessage `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *rpcError       `json:"error"`
+		}
+		if err := json.Unmarshal(data, &reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Error != nil {
+			t.Fatalf("request %s: %s", reply.ID, reply.Error.Message)
+		}
+		replies[string(reply.ID)] = reply.Result
+	}
+}
+
+func TestLSPRangesCountUTF16(t *testing.T) {
+	useDB(t, filepath.Join(t.TempDir(), "grb.db"))
+	if _, err := createSnippet("git status", "git", "gs", false); err != nil {
+		t.Fatal(err)
+	}
+	const uri = "file:///notes.md"
+	replies := lspSession(t,
+		map[string]interface{}{"method": "textDocument/didOpen", "params": map[string]interface{}{
+			"textDocument": map[string]string{"uri": uri, "languageId": "markdown", "text": lspDoc},
+		}},
+		map[string]interface{}{"id": 1, "method": "textDocument/completion", "params": map[string]interface{}{
+			"textDocument": map[string]string{"uri": uri}, "position": lspPosition{1, 7},
+		}},
+		map[string]interface{}{"id": 2, "method": "textDocument/hover", "params": map[string]interface{}{
+			"textDocument": map[string]string{"uri": uri}, "position": lspPosition{1, 6},
+		}},
+		map[string]interface{}{"id": 3, "method": "shutdown"},
+		map[string]interface{}{"method": "exit"},
+	)
+
+	var completion struct {
+		Items []struct {
+			Label    string `json:"label"`
+			TextEdit struct {
+				Range   lspRange `json:"range"`
+				NewText string   `json:"newText"`
+			} `json:"textEdit"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(replies["1"], &completion); err != nil {
+		t.Fatal(err)
+	}
+	if len(completion.Items) != 1 {
+		t.Fatalf("completion = %s", replies["1"])
+	}
+	// "g" after "😀 日本 " is at code unit 6, not byte 12 or rune 5.
+	if got, want := completion.Items[0].TextEdit.Range, (lspRange{lspPosition{1, 6}, lspPosition{1, 7}}); got != want {
+		t.Errorf("completion replaces %+v, want %+v", got, want)
+	}
+
+	var hover struct {
+		Contents struct {
+			Value string `json:"value"`
+		} `json:"contents"`
+		Range lspRange `json:"range"`
+	}
+	if err := json.Unmarshal(replies["2"], &hover); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(hover.Contents.Value, "git status") {
+		t.Errorf("hover = %q, want the snippet", hover.Contents.Value)
+	}
+	// The trailing "." is not part of the alias.
+	if got, want := hover.Range, (lspRange{lspPosition{1, 6}, lspPosition{1, 8}}); got != want {
+		t.Errorf("hover range = %+v, want %+v", got, want)
+	}
+}
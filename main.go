@@ -1,19 +1,28 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
 	"unicode/utf8"
-    
+
 	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"go.etcd.io/bbolt"
@@ -23,11 +32,23 @@ import (
 
 var db *bbolt.DB
 
-// DB schema: text|tag|alias|pinned|useCount|createdAt
+// DB schema: text|tag|alias|pinned|useCount|createdAt|uuid|clocks|secret|v2
+//
+// Records ending in the "v2" marker escape "\" and "|" inside fields with a
+// backslash so any text round-trips. Older records have no marker and no
+// escaping; they are still read, and rewritten as v2 on their next update.
 
 // ------------------ DB PATH ------------------
 
+// getDBPath returns the database chosen by the config, GRB_DB or --db.
 func getDBPath() string {
+	if active.DB == "" {
+		return defaultDBPath()
+	}
+	return expandHome(active.DB)
+}
+
+func defaultDBPath() string {
 	if runtime.GOOS == "windows" {
 		appdata := os.Getenv("APPDATA")
 		return filepath.Join(appdata, "grb", "grb.db")
@@ -36,24 +57,309 @@ func getDBPath() string {
 	return filepath.Join(home, ".grb", "grb.db")
 }
 
-func initDB() {
+// lockTimeout bounds how long a command waits for another grb process
+// (the daemon or a TUI) to release the database.
+const lockTimeout = 5 * time.Second
+
+func initDB() error {
 	dbPath := getDBPath()
-	os.MkdirAll(filepath.Dir(dbPath), 0755)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return err
+	}
+
+	if err := acquireDB(); err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte("snippets")); err != nil {
+			return err
+		}
+		if err := loadNodeID(tx); err != nil {
+			return err
+		}
+		loadEncryption(tx)
+		if err := clockLegacyOnce(tx); err != nil {
+			return err
+		}
+		if tx.Bucket([]byte("aliases")) == nil {
+			return rebuildAliasIndex(tx, defaultVault)
+		}
+		return nil
+	})
+}
 
+// acquireDB opens the database if it is not already open. Long-running
+// modes (daemon, TUI) release it between operations so other grb processes
+// can take the file lock.
+func acquireDB() error {
+	if db != nil {
+		return nil
+	}
 	var err error
-	db, err = bbolt.Open(dbPath, 0600, nil)
+	db, err = bbolt.Open(getDBPath(), 0600, &bbolt.Options{Timeout: lockTimeout})
+	if err == bbolt.ErrTimeout {
+		return ErrLocked
+	}
+	return err
+}
+
+// releaseDB closes the database so another grb process can open it.
+func releaseDB() {
+	if db != nil {
+		db.Close()
+		db = nil
+	}
+}
+
+// ------------------ RECORDS ------------------
+
+// snippet is a decoded row of a vault's snippets bucket.
+type snippet struct {
+	vault    string // set by loaders that read several vaults; not stored
+	project  bool   // read-only snippet from the project's .grb.yaml
+	id       string
+	text     string
+	tag      string
+	alias    string
+	pinned   bool
+	secret   bool // masked in listings, cleared from the clipboard after a while
+	archived bool // hidden from list, search and the TUI unless asked for
+	useCount int
+	created  int64
+	uuid     string
+	clocks   map[string]stamp  // per-field write clocks, see crdt.go
+	loaded   map[string]string // field values as read, to see what changed
+	sealed   string            // stored ciphertext of text in an encrypted DB
+}
+
+// recordMarker is the last field of every escaped (v2) record.
+const recordMarker = "v2"
+
+// splitRecord splits a stored value into its fields, undoing the escaping
+// of v2 records.
+func splitRecord(v []byte) []string {
+	raw := string(v)
+	if !strings.HasSuffix(raw, "|"+recordMarker) {
+		return strings.Split(raw, "|")
+	}
+	raw = strings.TrimSuffix(raw, "|"+recordMarker)
+
+	var fields []string
+	var field strings.Builder
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case raw[i] == '\\' && i+1 < len(raw):
+			i++
+			field.WriteByte(raw[i])
+		case raw[i] == '|':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(raw[i])
+		}
+	}
+	return append(fields, field.String())
+}
+
+// joinRecord escapes and joins fields into a v2 record.
+func joinRecord(fields ...string) []byte {
+	escape := strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	escaped := make([]string, len(fields), len(fields)+1)
+	for i, f := range fields {
+		escaped[i] = escape.Replace(f)
+	}
+	return []byte(strings.Join(append(escaped, recordMarker), "|"))
+}
+
+// parseSnippet decodes a stored record, tolerating short legacy values.
+func parseSnippet(k, v []byte) snippet {
+	fields := splitRecord(v)
+	for len(fields) < 6 {
+		fields = append(fields, "")
+	}
+	s := snippet{
+		id:     string(k),
+		tag:    fields[1],
+		alias:  fields[2],
+		pinned: fields[3] == "true",
+	}
+	fmt.Sscanf(fields[4], "%d", &s.useCount)
+	fmt.Sscanf(fields[5], "%d", &s.created)
+	s.text, s.sealed = openText(fields[0])
+	if len(fields) >= 8 {
+		s.uuid = fields[6]
+		s.clocks = decodeClocks(fields[7])
+	}
+	if len(fields) >= 9 {
+		s.secret = fields[8] == "true"
+	}
+	if len(fields) >= 10 {
+		s.archived = fields[9] == "true"
+	}
+	s.loaded = s.fieldValues()
+	return s
+}
+
+// encode renders the snippet back into the stored record format, giving
+// new snippets a UUID and stamping the clocks of changed fields.
+func (s snippet) encode() ([]byte, error) {
+	if s.uuid == "" {
+		s.uuid = newUUID()
+	}
+	text, err := sealText(s)
+	if err != nil {
+		return nil, err
+	}
+	return joinRecord(text, s.tag, s.alias,
+		fmt.Sprintf("%t", s.pinned),
+		fmt.Sprintf("%d", s.useCount),
+		fmt.Sprintf("%d", s.created),
+		s.uuid,
+		encodeClocks(s.stampClocks()),
+		fmt.Sprintf("%t", s.secret),
+		fmt.Sprintf("%t", s.archived)), nil
+}
+
+// putSnippet stores s under id in b.
+func putSnippet(b *bbolt.Bucket, id string, s snippet) error {
+	v, err := s.encode()
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(id), v)
+}
+
+// findSnippet returns the snippet whose id or alias is idOrAlias.
+func findSnippet(tx *bbolt.Tx, vault, idOrAlias string) (snippet, bool) {
+	b := tx.Bucket(snippetsKey(vault))
+	if v := b.Get([]byte(idOrAlias)); v != nil {
+		return parseSnippet([]byte(idOrAlias), v), true
+	}
+	if idx := tx.Bucket(aliasesKey(vault)); idx != nil {
+		if id := idx.Get([]byte(idOrAlias)); id != nil {
+			if v := b.Get(id); v != nil {
+				return parseSnippet(id, v), true
+			}
+		}
+	}
+	return snippet{}, false
+}
+
+// ------------------ ALIAS INDEX ------------------
+
+// DB schema (aliases bucket): alias -> id
+//
+// The index is the source of truth for alias ownership: every write that
+// sets or drops an alias updates it in the same transaction.
+
+// validateAlias rejects aliases that would be ambiguous on the command line.
+func validateAlias(alias string) error {
+	switch {
+	case alias == "":
+		return nil
+	case strings.IndexFunc(alias, unicode.IsSpace) >= 0:
+		return usagef("alias %q must not contain whitespace", alias)
+	case strings.Trim(alias, "0123456789") == "":
+		return usagef("alias %q is numeric and would clash with snippet ids", alias)
+	case strings.HasPrefix(alias, "-"):
+		return usagef("alias %q must not start with '-'", alias)
+	case strings.Contains(alias, "|"):
+		return usagef("alias %q must not contain '|'", alias)
+	}
+	return nil
+}
+
+// claimAlias points alias at id, failing if another snippet owns it.
+func claimAlias(tx *bbolt.Tx, vault, alias, id string) error {
+	if alias == "" {
+		return nil
+	}
+	if err := validateAlias(alias); err != nil {
+		return err
+	}
+	idx, err := tx.CreateBucketIfNotExists(aliasesKey(vault))
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if owner := idx.Get([]byte(alias)); owner != nil && string(owner) != id {
+		return fmt.Errorf("%w: %q belongs to snippet [%s]", ErrAliasTaken, alias, owner)
 	}
+	return idx.Put([]byte(alias), []byte(id))
+}
+
+// releaseAlias drops alias from the index if it belongs to id.
+func releaseAlias(tx *bbolt.Tx, vault, alias, id string) error {
+	idx := tx.Bucket(aliasesKey(vault))
+	if alias == "" || idx == nil {
+		return nil
+	}
+	if owner := idx.Get([]byte(alias)); string(owner) == id {
+		return idx.Delete([]byte(alias))
+	}
+	return nil
+}
 
-	db.Update(func(tx *bbolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte("snippets"))
+// rebuildAliasIndex recreates the index from the snippets bucket. When
+// older data has duplicate aliases the lowest id keeps the alias.
+func rebuildAliasIndex(tx *bbolt.Tx, vault string) error {
+	if tx.Bucket(aliasesKey(vault)) != nil {
+		if err := tx.DeleteBucket(aliasesKey(vault)); err != nil {
+			return err
+		}
+	}
+	idx, err := tx.CreateBucket(aliasesKey(vault))
+	if err != nil {
 		return err
+	}
+	return tx.Bucket(snippetsKey(vault)).ForEach(func(k, v []byte) error {
+		s := parseSnippet(k, v)
+		if s.alias == "" || idx.Get([]byte(s.alias)) != nil {
+			return nil
+		}
+		return idx.Put([]byte(s.alias), k)
 	})
 }
 
+// splitTags returns the individual tags of a comma-separated tag field.
+func splitTags(tag string) []string {
+	var tags []string
+	for _, t := range strings.Split(tag, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// addTag appends t to a tag field unless it is already present.
+func addTag(tag, t string) string {
+	tags := splitTags(tag)
+	for _, existing := range tags {
+		if existing == t {
+			return tag
+		}
+	}
+	return strings.Join(append(tags, t), ",")
+}
+
 // ------------------ TABLE HELPER ------------------
 
+// orDash shows empty table cells as "-".
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// oneLine flattens multi-line snippets for table cells.
+func oneLine(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\n", " ⏎ ")
+	return strings.ReplaceAll(s, "\t", " ")
+}
+
 // stripAnsi removes ANSI color codes to get the actual text length
 func stripAnsi(str string) string {
 	result := ""
@@ -89,186 +395,432 @@ func printSnippetTable(rows [][]string) {
 		return
 	}
 
-	// Calculate column widths based on actual text (not ANSI codes)
-	
-	
+	// A fifth column holds the vault in cross-vault results.
+	withVault := len(rows[0]) >= 5
+	top, mid, bottom, head := "", "", "", ""
+	if withVault {
+		top, mid, bottom = "┬──────────────────────┐", "┼──────────────────────┤", "┴──────────────────────┘"
+		head = fmt.Sprintf(" %-20s │", "Vault")
+	} else {
+		top, mid, bottom = "┐", "┤", "┘"
+	}
+
 	// Header
-	fmt.Println("┌─────┬────────────────────────────────────────────────────┬──────────────────────┬──────────────────────┐")
-	fmt.Printf("│ %-3s │ %-50s │ %-20s │ %-20s │\n", "ID", "Snippet", "Tag", "Alias")
-	fmt.Println("├─────┼────────────────────────────────────────────────────┼──────────────────────┼──────────────────────┤")
+	fmt.Println("┌─────┬────────────────────────────────────────────────────┬──────────────────────┬──────────────────────" + top)
+	fmt.Printf("│ %-3s │ %-50s │ %-20s │ %-20s │%s\n", "ID", "Snippet", "Tag", "Alias", head)
+	fmt.Println("├─────┼────────────────────────────────────────────────────┼──────────────────────┼──────────────────────" + mid)
 
 	// Rows
 	for _, row := range rows {
 		if len(row) >= 4 {
 			// Truncate long snippets
-			snippet := row[1]
+			snippet := oneLine(row[1])
 			if getDisplayWidth(snippet) > 50 {
-				snippet = stripAnsi(snippet)[:47] + "..."
+				snippet = string([]rune(stripAnsi(snippet))[:47]) + "..."
+			}
+
+			extra := ""
+			if withVault && len(row) >= 5 {
+				extra = " " + padRight(row[4], 20) + " │"
 			}
-			
-			fmt.Printf("│ %s │ %s │ %s │ %s │\n",
+			fmt.Printf("│ %s │ %s │ %s │ %s │%s\n",
 				padRight(row[0], 3),
 				padRight(snippet, 50),
 				padRight(row[2], 20),
-				padRight(row[3], 20))
+				padRight(row[3], 20),
+				extra)
 		}
 	}
-	
-	fmt.Println("└─────┴────────────────────────────────────────────────────┴──────────────────────┴──────────────────────┘")
+
+	fmt.Println("└─────┴────────────────────────────────────────────────────┴──────────────────────┴──────────────────────" + bottom)
 }
 
 // ------------------ MAIN ------------------
 
 func main() {
-	initDB()
-	defer db.Close()
-
 	rootCmd := &cobra.Command{
-    Use:   "grb",
-    Short: "grb - Smart Clipboard & Snippet Manager",
-    Run: func(cmd *cobra.Command, args []string) {
-        fmt.Println("💡 Tip: Run \"grb help\" to see all commands and features")
-        launchTUI() // default = TUI
-    },
-}
-
-rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
-    cyan := color.New(color.FgCyan).SprintFunc()
-    green := color.New(color.FgGreen).SprintFunc()
-    yellow := color.New(color.FgYellow).SprintFunc()
-
-    fmt.Println("─────────────────────────────────────────────")
-    fmt.Println("   grb (grab) - Smart Clipboard Manager")
-    fmt.Println("─────────────────────────────────────────────\n")
-
-    fmt.Println(cyan("📦 Features"))
-    fmt.Println("─────────────────────────────────────────────")
-    fmt.Printf("%s %-22s %s\n", green("✔"), "Save snippets", yellow("grb save \"text\" --tag t --alias a"))
-    fmt.Printf("%s %-22s %s\n", green("✔"), "Auto-copy on save", "(copies immediately to clipboard)")
-    fmt.Printf("%s %-22s %s\n", green("✔"), "List all snippets", "grb list")
-    fmt.Printf("%s %-22s %s\n", green("✔"), "Search snippets", "grb search <word>")
-    fmt.Printf("%s %-22s %s\n", green("✔"), "Copy snippet", "grb copy <id|alias>")
-    fmt.Printf("%s %-22s %s\n", green("✔"), "Pin/Unpin snippet", "grb pin <id|alias>")
-	fmt.Printf("%s %-22s %s\n", green("✔"), "Update alias", "grb alias <id|oldAlias> <newAlias>")
-fmt.Printf("%s %-22s %s\n", green("✔"), "Delete snippet", "grb delete <id|alias>")
-fmt.Printf("%s %-22s %s\n", green("✔"), "Clear snippets", "grb clear --all/--tag/--unpinned")
-
-    fmt.Printf("%s %-22s %s\n", green("✔"), "Edit snippet", "grb edit <id|alias>")
-    fmt.Printf("%s %-22s %s\n", green("✔"), "Show usage stats", "grb stats")
-    fmt.Printf("%s %-22s %s\n", green("✔"), "Clipboard history", "grb daemon")
-    fmt.Printf("%s %-22s %s\n", green("✔"), "Interactive TUI", "grb tui   (or just 'grb')")
-
-    fmt.Println("\n📋 Notes")
-    fmt.Println("─────────────────────────────────────────────")
-    fmt.Println("Data is stored persistently at:")
-    fmt.Println("   %APPDATA%\\grb   (Windows)")
-    fmt.Println("   ~/.grb          (Linux/Mac)")
-
-    fmt.Println("\n💡 Tip: Run 'grb' with no command to launch TUI.\n")
-})
+		Use:           "grb",
+		Short:         "grb - Smart Clipboard & Snippet Manager",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		// Settings depend on --profile and --db, so they are loaded once
+		// flags are parsed. 'grb config' works without opening the database.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if cfg, err = loadConfig(); err != nil {
+				return err
+			}
+			profileFlag, _ := cmd.Flags().GetString("profile")
+			dbFlag, _ := cmd.Flags().GetString("db")
+			if err := resolveSettings(profileFlag, dbFlag); err != nil {
+				return err
+			}
+			applyTheme(active.Theme)
+			for c := cmd; c != nil; c = c.Parent() {
+				if c.Annotations["db"] == "none" {
+					return nil
+				}
+			}
+			if err := initDB(); err != nil {
+				return err
+			}
+			vaultFlag, _ := cmd.Flags().GetString("vault")
+			if err := selectVault(vaultFlag); err != nil {
+				return err
+			}
+			return openSession(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Println("💡 Tip: Run \"grb help\" to see all commands and features")
+			return launchTUI("\n") // default = TUI
+		},
+	}
+	rootCmd.PersistentFlags().String("profile", "", "Use a profile from config.toml (or GRB_PROFILE)")
+	rootCmd.PersistentFlags().String("db", "", "Use this database file (or GRB_DB)")
+	rootCmd.PersistentFlags().String("vault", "", "Use this vault (or GRB_VAULT, see 'grb vault use')")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError{err.Error()}
+	})
+
+	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		accent := theme.accent.SprintFunc()
+		success := theme.success.SprintFunc()
+		highlight := theme.highlight.SprintFunc()
+
+		fmt.Println("─────────────────────────────────────────────")
+		fmt.Println("   grb (grab) - Smart Clipboard Manager")
+		fmt.Println("─────────────────────────────────────────────")
+		fmt.Println()
+
+		fmt.Println(accent("📦 Features"))
+		fmt.Println("─────────────────────────────────────────────")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Save snippets", highlight("grb save \"text\" --tag t --alias a"))
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Auto-copy on save", "(copies immediately to clipboard)")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "List all snippets", "grb list")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Search snippets", "grb search <word>")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Copy snippet", "grb copy <id|alias>")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Print snippet", "grb get <id|alias> [--var k=v]")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Run snippet", "grb run <alias> -- args")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Pin/Unpin snippet", "grb pin <id|alias>")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Secret snippets", "grb save --secret, grb secret <id|alias>")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Update alias", "grb alias <id|oldAlias> <newAlias>")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Delete snippet", "grb delete <id|alias>")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Clear snippets", "grb clear --all/--tag/--unpinned")
+
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Edit snippet", "grb edit <id|alias>")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Show usage stats", "grb stats --since 30d")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Review unused snippets", "grb review, grb archive <id|alias>")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Import snippets", "grb import --from vscode|espanso|pet|cheat|navi|csv <path>")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Export for editors", "grb export --format vscode|sublime|vim-ultisnips|espanso|markdown")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "JSON API", "grb serve --addr 127.0.0.1:7777")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Editor integration", "grb lsp   (completion, hover, save selection)")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "JSON-RPC / MCP tools", "grb rpc --stdio")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Clipboard history", "grb daemon")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Interactive TUI", "grb tui   (or just 'grb')")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Bulk actions (TUI)", "space to mark, then x/p/t/e/y")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Saved views", "grb view save|list|rm  ('v' in TUI)")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Settings & profiles", "grb config get|set|list|edit, --profile, --db")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Project snippets", ".grb.yaml in the repo, grb save --project")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Git sync", "grb sync init <repo>, then grb sync")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Folder sync", "grb sync --dir <shared-folder>")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Check database", "grb doctor [--fix]")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Backup & restore", "grb backup [path], grb backup list, grb restore <file>")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Encryption", "grb encrypt --migrate, grb unlock/lock")
+		fmt.Printf("%s %-22s %s\n", success("✔"), "Vaults", "grb vault create|list|use|rm, grb mv <id> --to v")
+
+		fmt.Println("\n📋 Notes")
+		fmt.Println("─────────────────────────────────────────────")
+		fmt.Println("Data is stored persistently at:")
+		fmt.Println("   %APPDATA%\\grb   (Windows)")
+		fmt.Println("   ~/.grb          (Linux/Mac)")
+
+		fmt.Println("\n💡 Tip: Run 'grb' with no command to launch TUI.")
+		fmt.Println()
+	})
 
 	// ------------------ SAVE ------------------
 	saveCmd := &cobra.Command{
-		Use:   "save [text]",
+		Use:   "save [text|-]",
 		Short: "Save a snippet (auto copies too)",
-		Run: func(cmd *cobra.Command, args []string) {
-			if len(args) == 0 {
-				fmt.Println("Provide text to save")
-				return
+		RunE: func(cmd *cobra.Command, args []string) error {
+			text, err := snippetInput(cmd, args)
+			if err != nil {
+				return err
 			}
-			text := strings.Join(args, " ")
 			tag, _ := cmd.Flags().GetString("tag")
 			alias, _ := cmd.Flags().GetString("alias")
-			saveSnippet(text, tag, alias)
+			noCopy, _ := cmd.Flags().GetBool("no-copy")
+			secret, _ := cmd.Flags().GetBool("secret")
+			if project, _ := cmd.Flags().GetBool("project"); project {
+				if secret {
+					return usagef("Project snippets are committed in plain text and can't be secret")
+				}
+				return saveToProject(text, tag, alias, autoCopy() && !noCopy)
+			}
+			return saveSnippet(text, tag, alias, secret, autoCopy() && !noCopy)
 		},
 	}
 	saveCmd.Flags().String("tag", "", "Add a tag")
 	saveCmd.Flags().String("alias", "", "Give an alias")
+	saveCmd.Flags().String("file", "", "Read the snippet from a file")
+	saveCmd.Flags().Bool("from-clipboard", false, "Save the current clipboard contents")
+	saveCmd.Flags().Bool("editor", false, "Write the snippet in your editor")
+	saveCmd.Flags().Bool("no-copy", false, "Don't copy the snippet to the clipboard")
+	saveCmd.Flags().Bool("project", false, "Save into the project's .grb.yaml instead of the vault")
+	saveCmd.Flags().Bool("secret", false, "Mask the snippet in listings and clear it from the clipboard after clear_after")
 	rootCmd.AddCommand(saveCmd)
 
 	// ------------------ LIST ------------------
-	rootCmd.AddCommand(&cobra.Command{
+	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List snippets",
-		Run: func(cmd *cobra.Command, args []string) {
-			listSnippets()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			showArchived, _ = cmd.Flags().GetBool("archived")
+			return listSnippets()
 		},
-	})
+	}
+	listCmd.Flags().Bool("archived", false, "List archived snippets instead")
+	rootCmd.AddCommand(listCmd)
 
 	// ------------------ DELETE ------------------
-rootCmd.AddCommand(&cobra.Command{
-    Use:   "delete [id|alias]",
-    Short: "Delete a snippet",
-    Run: func(cmd *cobra.Command, args []string) {
-        if len(args) == 0 {
-            fmt.Println("Provide snippet id or alias to delete")
-            return
-        }
-        deleteSnippet(args[0])
-    },
-})
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "delete [id|alias]",
+		Short: "Delete a snippet",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide snippet id or alias to delete")
+			}
+			return deleteSnippet(args[0])
+		},
+	})
 
-// ------------------ CLEAR ------------------
-clearCmd := &cobra.Command{
-    Use:   "clear",
-    Short: "Clear snippets (dangerous!)",
-}
-clearCmd.Flags().Bool("all", false, "Delete all snippets")
-clearCmd.Flags().String("tag", "", "Delete all snippets with a tag")
-clearCmd.Flags().Bool("unpinned", false, "Delete all unpinned snippets")
-clearCmd.Run = func(cmd *cobra.Command, args []string) {
-    all, _ := cmd.Flags().GetBool("all")
-    tag, _ := cmd.Flags().GetString("tag")
-    unpinned, _ := cmd.Flags().GetBool("unpinned")
-    clearSnippets(all, tag, unpinned)
-}
-rootCmd.AddCommand(clearCmd)
+	// ------------------ CLEAR ------------------
+	clearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Clear snippets (dangerous!)",
+	}
+	clearCmd.Flags().Bool("all", false, "Delete all snippets")
+	clearCmd.Flags().String("tag", "", "Delete all snippets with a tag")
+	clearCmd.Flags().Bool("unpinned", false, "Delete all unpinned snippets")
+	clearCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		tag, _ := cmd.Flags().GetString("tag")
+		unpinned, _ := cmd.Flags().GetBool("unpinned")
+		if !all && tag == "" && !unpinned {
+			return usagef("Use one of --all, --tag or --unpinned")
+		}
+		return clearSnippets(all, tag, unpinned)
+	}
+	rootCmd.AddCommand(clearCmd)
 
 	// ------------------ SEARCH ------------------
-	rootCmd.AddCommand(&cobra.Command{
+	searchCmd := &cobra.Command{
 		Use:   "search [query]",
 		Short: "Search snippets",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				fmt.Println("Provide a search term")
-				return
+				return usagef("Provide a search term")
 			}
-			searchSnippets(args[0])
+			allVaults, _ = cmd.Flags().GetBool("all-vaults")
+			showArchived, _ = cmd.Flags().GetBool("archived")
+			return searchSnippets(args[0])
 		},
-	})
+	}
+	searchCmd.Flags().Bool("all-vaults", false, "Search every vault")
+	searchCmd.Flags().Bool("archived", false, "Search archived snippets instead")
+	rootCmd.AddCommand(searchCmd)
 
 	// ------------------ COPY ------------------
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "copy [id|alias]",
 		Short: "Copy snippet to clipboard",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				fmt.Println("Provide snippet id or alias")
-				return
+				return usagef("Provide snippet id or alias")
 			}
-			copySnippet(args[0])
+			return copySnippet(args[0])
 		},
 	})
-	
+
+	// ------------------ GET ------------------
+	getCmd := &cobra.Command{
+		Use:     "get [id|alias]",
+		Aliases: []string{"paste"},
+		Short:   "Print a snippet's raw text to stdout",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide snippet id or alias")
+			}
+			pairs, _ := cmd.Flags().GetStringArray("var")
+			render, _ := cmd.Flags().GetBool("render")
+			return getSnippet(args[0], pairs, render)
+		},
+	}
+	getCmd.Flags().StringArray("var", nil, "Fill a {{placeholder}}: name=value (repeatable)")
+	getCmd.Flags().Bool("render", false, "Fill placeholders from their defaults")
+	rootCmd.AddCommand(getCmd)
+
+	// ------------------ RUN ------------------
+	runCmd := &cobra.Command{
+		Use:   "run [id|alias] -- [args...]",
+		Short: "Run a snippet as a shell command",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide snippet id or alias")
+			}
+			pairs, _ := cmd.Flags().GetStringArray("var")
+			yes, _ := cmd.Flags().GetBool("yes")
+			return runSnippet(args[0], args[1:], pairs, yes)
+		},
+	}
+	runCmd.Flags().StringArray("var", nil, "Fill a {{placeholder}}: name=value (repeatable)")
+	runCmd.Flags().BoolP("yes", "y", false, "Run without asking for confirmation")
+	rootCmd.AddCommand(runCmd)
+
 	// ✅ Add this
-	rootCmd.AddCommand(&cobra.Command{
-    Use:   "tui",
-    Short: "Launch interactive TUI mode",
-    Run: func(cmd *cobra.Command, args []string) {
-        launchTUI()
-    },
-})
+	tuiCmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Launch interactive TUI mode",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sep, _ := cmd.Flags().GetString("sep")
+			allVaults, _ = cmd.Flags().GetBool("all-vaults")
+			showArchived, _ = cmd.Flags().GetBool("archived")
+			return launchTUI(unescapeSeparator(sep))
+		},
+	}
+	tuiCmd.Flags().String("sep", "\n", "Separator used when copying or exporting marked snippets")
+	tuiCmd.Flags().Bool("all-vaults", false, "Show snippets from every vault")
+	tuiCmd.Flags().Bool("archived", false, "Show archived snippets instead")
+	rootCmd.AddCommand(tuiCmd)
+
+	// ------------------ VIEWS ------------------
+	viewCmd := &cobra.Command{
+		Use:   "view",
+		Short: "Manage saved TUI views",
+	}
+	viewSaveCmd := &cobra.Command{
+		Use:   "save [name]",
+		Short: "Save a view filtering by tag and/or text",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide a name for the view")
+			}
+			tag, _ := cmd.Flags().GetString("tag")
+			query, _ := cmd.Flags().GetString("query")
+			if err := saveView(args[0], tag, query); err != nil {
+				return err
+			}
+			say(theme.success, "⭐ Saved view %s", args[0])
+			fmt.Println("💡 Tip: Press 'v' in the TUI to open it")
+			return nil
+		},
+	}
+	viewSaveCmd.Flags().String("tag", "", "Only show snippets with this tag")
+	viewSaveCmd.Flags().String("query", "", "Only show snippets matching these words")
+	viewCmd.AddCommand(viewSaveCmd)
+	viewCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List saved views",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			views := loadSavedViews()
+			if len(views) == 0 {
+				say(theme.highlight, "⚠ No saved views.")
+				fmt.Println("💡 Tip: Use 'grb view save <name> --tag t' to create one")
+				return nil
+			}
+			for _, v := range views {
+				fmt.Printf("%s  tag=%s  query=%s\n", theme.highlight.Sprint(v.name), v.tag, v.query)
+			}
+			return nil
+		},
+	})
+	viewCmd.AddCommand(&cobra.Command{
+		Use:   "rm [name]",
+		Short: "Delete a saved view",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide the name of the view")
+			}
+			found, err := deleteView(args[0])
+			if err != nil {
+				return err
+			}
+			if !found {
+				return fmt.Errorf("view %w: %s", ErrNotFound, args[0])
+			}
+			say(theme.danger, "🗑 View deleted!")
+			return nil
+		},
+	})
+	rootCmd.AddCommand(viewCmd)
 
 	// ------------------ PIN ------------------
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "pin [id|alias]",
 		Short: "Pin/unpin a snippet",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide snippet id or alias")
+			}
+			return pinSnippet(args[0])
+		},
+	})
+
+	// ------------------ SECRET ------------------
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "secret [id|alias]",
+		Short: "Mark/unmark a snippet as secret",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide snippet id or alias")
+			}
+			return toggleSecret(args[0])
+		},
+	})
+	// ------------------ REVIEW ------------------
+	reviewCmd := &cobra.Command{
+		Use:   "review",
+		Short: "Keep, archive, delete, retag or edit snippets nobody uses",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			unused := reviewAfter()
+			if cmd.Flags().Changed("unused") {
+				v, _ := cmd.Flags().GetString("unused")
+				var err error
+				if unused, err = parseSince(v); err != nil {
+					return err
+				}
+			}
+			maxUses, _ := cmd.Flags().GetInt("max-uses")
+			return reviewSnippets(unused, maxUses)
+		},
+	}
+	reviewCmd.Flags().String("unused", "", "Offer snippets not used for this long (default review_after, 90d)")
+	reviewCmd.Flags().Int("max-uses", 2, "Only offer snippets used at most this many times")
+	rootCmd.AddCommand(reviewCmd)
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "archive [id|alias]",
+		Short: "Archive a snippet, or restore an archived one",
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				fmt.Println("Provide snippet id or alias")
-				return
+				return usagef("Provide snippet id or alias")
 			}
-			pinSnippet(args[0])
+			return toggleArchive(args[0])
+		},
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:         "clipboard-clear [duration]",
+		Short:       "Clear the clipboard later if it still holds a secret (used by copy)",
+		Hidden:      true,
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{"db": "none"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			after, err := time.ParseDuration(args[0])
+			if err != nil {
+				return usageError{err.Error()}
+			}
+			return clearClipboardLater(after)
 		},
 	})
 
@@ -276,829 +828,2552 @@ rootCmd.AddCommand(clearCmd)
 	rootCmd.AddCommand(&cobra.Command{
 		Use:   "edit [id|alias]",
 		Short: "Edit a snippet in default editor",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				fmt.Println("Provide snippet id or alias to edit")
-				return
+				return usagef("Provide snippet id or alias to edit")
 			}
-			editSnippet(args[0])
+			return editSnippet(args[0])
 		},
 	})
 
 	// ------------------ ALIAS ------------------
-	rootCmd.AddCommand(&cobra.Command{
-    Use:   "alias [id|oldAlias] [newAlias]",
-    Short: "Update alias for a snippet",
-    Run: func(cmd *cobra.Command, args []string) {
-        if len(args) < 2 {
-            fmt.Println("Usage: grb alias [id|oldAlias] [newAlias]")
-            return
-        }
-        updateAlias(args[0], args[1])
-    },
-})
+	aliasCmd := &cobra.Command{
+		Use:   "alias [id|oldAlias] [newAlias]",
+		Short: "Update alias for a snippet",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list, _ := cmd.Flags().GetBool("list"); list {
+				return listAliases()
+			}
+			if len(args) < 2 {
+				return usagef("Usage: grb alias [id|oldAlias] [newAlias]")
+			}
+			return updateAlias(args[0], args[1])
+		},
+	}
+	aliasCmd.Flags().Bool("list", false, "List all aliases")
+	rootCmd.AddCommand(aliasCmd)
+
+	// ------------------ IMPORT ------------------
+	importCmd := &cobra.Command{
+		Use:   "import --from [format] [path]",
+		Short: "Import snippets from VS Code, Espanso, pet, cheat, navi or CSV",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, _ := cmd.Flags().GetString("from")
+			if from == "" || len(args) == 0 {
+				return usagef("Use 'grb import --from <%s> <path>'", strings.Join(importFormats(), "|"))
+			}
+			yes, _ := cmd.Flags().GetBool("yes")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			return importSnippets(from, args[0], yes, dryRun)
+		},
+	}
+	importCmd.Flags().String("from", "", "Format to read: "+strings.Join(importFormats(), ", "))
+	importCmd.Flags().BoolP("yes", "y", false, "Import without asking for confirmation")
+	importCmd.Flags().Bool("dry-run", false, "Only show what would be imported")
+	rootCmd.AddCommand(importCmd)
+
+	// ------------------ EXPORT ------------------
+	exportCmd := &cobra.Command{
+		Use:   "export --format [format]",
+		Short: "Export snippets for VS Code, Sublime Text, UltiSnips, Espanso or Markdown",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			if format == "" {
+				return usagef("Use 'grb export --format <%s>'", strings.Join(exportFormats(), "|"))
+			}
+			out, _ := cmd.Flags().GetString("out")
+			tag, _ := cmd.Flags().GetString("tag")
+			return exportSnippets(format, out, tag)
+		},
+	}
+	exportCmd.Flags().String("format", "", "Format to write: "+strings.Join(exportFormats(), ", "))
+	exportCmd.Flags().StringP("out", "o", "", "File or directory to write (default depends on the format; - for stdout)")
+	exportCmd.Flags().String("tag", "", "Only export snippets with this tag")
+	rootCmd.AddCommand(exportCmd)
 
 	// ------------------ STATS ------------------
-	rootCmd.AddCommand(&cobra.Command{
+	statsCmd := &cobra.Command{
 		Use:   "stats",
 		Short: "Show snippet usage stats",
-		Run: func(cmd *cobra.Command, args []string) {
-			showStats()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sinceFlag, _ := cmd.Flags().GetString("since")
+			unusedFlag, _ := cmd.Flags().GetString("unused")
+			top, _ := cmd.Flags().GetInt("top")
+			since, err := parseSince(sinceFlag)
+			if err != nil {
+				return err
+			}
+			unused, err := parseSince(unusedFlag)
+			if err != nil {
+				return err
+			}
+			if top < 1 {
+				return usagef("--top must be at least 1")
+			}
+			if err := showStats(); err != nil {
+				return err
+			}
+			return printUsage(since, top, unused)
 		},
-	})
-
-	// ------------------ DAEMON ------------------
-	    rootCmd.AddCommand(&cobra.Command{
-        Use:   "daemon",
-        Short: "Run clipboard watcher (history mode)",
-        Run: func(cmd *cobra.Command, args []string) {
-            startDaemon()
-        },
-    })
-
-    if err := rootCmd.Execute(); err != nil {
-        fmt.Println(err)
-        os.Exit(1)
-    }
-}
+	}
+	statsCmd.Flags().String("since", "30d", "Period for usage, e.g. 7d, 2w, 12h")
+	statsCmd.Flags().Int("top", 5, "Number of most used snippets to show")
+	statsCmd.Flags().String("unused", "90d", "List snippets not used for this long")
+	rootCmd.AddCommand(statsCmd)
+
+	// ------------------ SERVE ------------------
+	serveCmd := &cobra.Command{
+		Use:         "serve",
+		Short:       "Serve a token-authenticated JSON API for scripts and extensions",
+		Annotations: map[string]string{"crypto": "none"}, // unlocked per request
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, _ := cmd.Flags().GetString("addr")
+			cors, _ := cmd.Flags().GetStringSlice("cors")
+			rotate, _ := cmd.Flags().GetBool("rotate-token")
+			return serveAPI(addr, cors, rotate)
+		},
+	}
+	serveCmd.Flags().String("addr", "127.0.0.1:7777", "Address to listen on")
+	serveCmd.Flags().StringSlice("cors", nil, "Origin allowed to call the API from a browser (repeatable, * for any)")
+	serveCmd.Flags().Bool("rotate-token", false, "Replace the API token with a new one")
+	rootCmd.AddCommand(serveCmd)
+
+	// ------------------ RPC ------------------
+	rpcCmd := &cobra.Command{
+		Use:         "rpc --stdio",
+		Short:       "Serve JSON-RPC (and MCP tools) on stdio for scripts and assistants",
+		Annotations: map[string]string{"crypto": "none"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if stdio, _ := cmd.Flags().GetBool("stdio"); !stdio {
+				return usagef("Use 'grb rpc --stdio' (stdio is the only transport)")
+			}
+			return runRPC(os.Stdin, os.Stdout)
+		},
+	}
+	rpcCmd.Flags().Bool("stdio", false, "Read requests from stdin and write replies to stdout, one JSON message per line")
+	rootCmd.AddCommand(rpcCmd)
 
-// ------------------ TUI ------------------
+	// ------------------ LSP ------------------
+	rootCmd.AddCommand(&cobra.Command{
+		Use:         "lsp",
+		Short:       "Run a language server on stdio for snippet completion in editors",
+		Annotations: map[string]string{"crypto": "none"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLSP(os.Stdin, os.Stdout)
+		},
+	})
 
-type item struct {
-    id      string
-    text    string
-    tag     string
-    alias   string
-    pin     string
-    section string // "header", "snippet"
-}
+	// ------------------ VAULT ------------------
+	vaultCmd := &cobra.Command{
+		Use:   "vault",
+		Short: "Manage vaults (separate snippet libraries)",
+	}
+	vaultCmd.AddCommand(&cobra.Command{
+		Use:   "create [name]",
+		Short: "Create an empty vault",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide a name for the vault")
+			}
+			if err := createVault(args[0]); err != nil {
+				return err
+			}
+			say(theme.success, "✅ Created vault %s", args[0])
+			fmt.Printf("💡 Tip: Use 'grb vault use %s' or --vault %s\n", args[0], args[0])
+			return nil
+		},
+	})
+	vaultCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List vaults",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printVaults()
+		},
+	})
+	vaultCmd.AddCommand(&cobra.Command{
+		Use:   "use [name]",
+		Short: "Use a vault when --vault is not given",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide the name of the vault")
+			}
+			if err := useVault(args[0]); err != nil {
+				return err
+			}
+			say(theme.success, "✅ Now using vault %s", args[0])
+			return nil
+		},
+	})
+	vaultRmCmd := &cobra.Command{
+		Use:   "rm [name]",
+		Short: "Delete a vault",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide the name of the vault")
+			}
+			force, _ := cmd.Flags().GetBool("force")
+			n, err := removeVault(args[0], force)
+			if err != nil {
+				return err
+			}
+			say(theme.danger, "🗑 Vault %s deleted (%d snippets)", args[0], n)
+			return nil
+		},
+	}
+	vaultRmCmd.Flags().Bool("force", false, "Delete the vault even if it has snippets")
+	vaultCmd.AddCommand(vaultRmCmd)
+	rootCmd.AddCommand(vaultCmd)
+
+	mvCmd := &cobra.Command{
+		Use:   "mv [id|alias] --to [vault]",
+		Short: "Move a snippet to another vault",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			to, _ := cmd.Flags().GetString("to")
+			if len(args) == 0 || to == "" {
+				return usagef("Usage: grb mv [id|alias] --to [vault]")
+			}
+			from := vault
+			s, err := moveSnippet(args[0], to)
+			if err != nil {
+				return err
+			}
+			accent := theme.accent.SprintFunc()
+			say(theme.success, "✅ Moved snippet from %s to %s", from, to)
+			printSnippetTable([][]string{
+				{accent(s.id), s.shownText(), theme.label.Sprint(orDash(s.tag)), theme.highlight.Sprint(orDash(s.alias)), accent(to)},
+			})
+			return nil
+		},
+	}
+	mvCmd.Flags().String("to", "", "Destination vault")
+	rootCmd.AddCommand(mvCmd)
+
+	// ------------------ SYNC ------------------
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Sync snippets through a git repository or a shared folder",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dir, _ := cmd.Flags().GetString("dir"); dir != "" {
+				return runDirSync(dir)
+			}
+			return runSync()
+		},
+	}
+	syncCmd.Flags().String("dir", "", "Merge through a shared folder (Dropbox, Syncthing, ...) instead of git")
+	syncCmd.PersistentFlags().BoolVar(&syncPlaintext, "allow-plaintext", false, "Sync an encrypted database even though the copies are plaintext")
+	syncCmd.AddCommand(&cobra.Command{
+		Use:   "init [repo-path-or-remote]",
+		Short: "Clone a git repository to sync snippets through",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide a repository path or remote URL")
+			}
+			return initSync(args[0])
+		},
+	})
+	rootCmd.AddCommand(syncCmd)
 
-func (i item) Title() string {
-    if i.section == "header" {
-        return color.CyanString(i.text)
-    }
-    if i.pin == "true" {
-        return color.YellowString("📌 %s", i.text)
-    }
-    return i.text
+	// ------------------ CONFIG ------------------
+	configCmd := &cobra.Command{
+		Use:         "config",
+		Short:       "Show or change settings in config.toml",
+		Annotations: map[string]string{"db": "none"},
+	}
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "get [key]",
+		Short: "Print the effective value of a setting",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide a setting: profile, %s", strings.Join(settingKeys, ", "))
+			}
+			return configGet(args[0])
+		},
+	})
+	configSetCmd := &cobra.Command{
+		Use:   "set [key] [value]",
+		Short: "Write a setting to config.toml",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return usagef("Usage: grb config set [key] [value] [--in profile]")
+			}
+			in, _ := cmd.Flags().GetString("in")
+			if err := configSet(args[0], args[1], in); err != nil {
+				return err
+			}
+			say(theme.success, "✅ %s = %s", args[0], args[1])
+			return nil
+		},
+	}
+	configSetCmd.Flags().String("in", "", "Write to [profiles.<name>] instead of the top level")
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List effective settings and where they come from",
+		Run: func(cmd *cobra.Command, args []string) {
+			configList()
+		},
+	})
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "edit",
+		Short: "Open config.toml in your editor",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return configEdit()
+		},
+	})
+	rootCmd.AddCommand(configCmd)
+
+	// ------------------ DAEMON ------------------
+	rootCmd.AddCommand(&cobra.Command{
+		Use:         "daemon",
+		Short:       "Run clipboard watcher (history mode)",
+		Annotations: map[string]string{"crypto": "none"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return startDaemon()
+		},
+	})
+
+	// ------------------ DOCTOR ------------------
+	doctorCmd := &cobra.Command{
+		Use:         "doctor",
+		Short:       "Check the database for malformed records, broken aliases and unreadable bookkeeping",
+		Annotations: map[string]string{"crypto": "none"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fix, _ := cmd.Flags().GetBool("fix")
+			return runDoctor(fix)
+		},
+	}
+	doctorCmd.Flags().Bool("fix", false, "Repair everything found, in one transaction")
+	rootCmd.AddCommand(doctorCmd)
+
+	// ------------------ BACKUP ------------------
+	backupCmd := &cobra.Command{
+		Use:         "backup [path]",
+		Short:       "Write a consistent copy of the database",
+		Annotations: map[string]string{"crypto": "none"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := ""
+			if len(args) > 0 {
+				path = args[0]
+			}
+			return backupDB(path)
+		},
+	}
+	backupCmd.AddCommand(&cobra.Command{
+		Use:         "list",
+		Short:       "List backups and daemon snapshots",
+		Annotations: map[string]string{"db": "none"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printBackups()
+		},
+	})
+	rootCmd.AddCommand(backupCmd)
+
+	restoreCmd := &cobra.Command{
+		Use:         "restore [file]",
+		Short:       "Replace the database with a backup",
+		Annotations: map[string]string{"crypto": "none"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return usagef("Provide a backup file (see 'grb backup list')")
+			}
+			yes, _ := cmd.Flags().GetBool("yes")
+			return restoreDB(args[0], yes)
+		},
+	}
+	restoreCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	rootCmd.AddCommand(restoreCmd)
+
+	// ------------------ ENCRYPTION ------------------
+	encryptCmd := &cobra.Command{
+		Use:         "encrypt",
+		Short:       "Encrypt snippet text with a passphrase",
+		Annotations: map[string]string{"crypto": "none"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			migrate, _ := cmd.Flags().GetBool("migrate")
+			return encryptDB(migrate)
+		},
+	}
+	encryptCmd.Flags().Bool("migrate", false, "Encrypt the snippets already in the database")
+	rootCmd.AddCommand(encryptCmd)
+
+	unlockCmd := &cobra.Command{
+		Use:         "unlock",
+		Short:       "Unlock an encrypted database for a while (needs 'grb daemon')",
+		Annotations: map[string]string{"crypto": "none"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ttl, _ := cmd.Flags().GetDuration("for")
+			if ttl <= 0 {
+				return usagef("--for must be positive")
+			}
+			return unlockSession(ttl)
+		},
+	}
+	unlockCmd.Flags().Duration("for", 15*time.Minute, "How long the daemon keeps the session")
+	rootCmd.AddCommand(unlockCmd)
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:         "lock",
+		Short:       "End the unlocked session",
+		Annotations: map[string]string{"crypto": "none"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lockSession()
+		},
+	})
+
+	err := rootCmd.Execute()
+	releaseDB()
+	if err != nil {
+		reportError(err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// ------------------ TUI ------------------
+
+type item struct {
+	vault   string
+	id      string
+	text    string
+	tag     string
+	alias   string
+	pin     string
+	secret  bool
+	section string // "header", "snippet", "project"
+	marked  bool
+}
+
+func (i item) Title() string {
+	if i.section == "header" {
+		return theme.accent.Sprint(i.text)
+	}
+	mark := ""
+	if i.marked {
+		mark = theme.success.Sprint("✔ ")
+	}
+	text := oneLine(i.text)
+	if i.secret {
+		text = secretMask
+	}
+	if i.pin == "true" {
+		return mark + theme.highlight.Sprintf("📌 %s", text)
+	}
+	return mark + text
 }
 
 func (i item) Description() string {
-    if i.section == "header" {
-        return ""
-    }
-    desc := ""
-    if i.tag != "" {
-        desc += color.MagentaString("🏷 %s  ", i.tag)
-    }
-    if i.alias != "" {
-        desc += color.YellowString("📖 %s", i.alias)
-    }
-    return desc
+	if i.section == "header" {
+		return ""
+	}
+	desc := ""
+	if i.section == "project" {
+		desc += theme.accent.Sprint("📁 project  ")
+	} else if allVaults {
+		desc += theme.accent.Sprintf("🗄 %s  ", i.vault)
+	}
+	if i.tag != "" {
+		desc += theme.label.Sprintf("🏷 %s  ", i.tag)
+	}
+	if i.alias != "" {
+		desc += theme.highlight.Sprintf("📖 %s", i.alias)
+	}
+	return desc
 }
 
+// FilterValue leaves out the text of secrets so filtering can't reveal it.
 func (i item) FilterValue() string {
-    return i.text + " " + i.tag + " " + i.alias
+	if i.secret {
+		return i.tag + " " + i.alias
+	}
+	return i.text + " " + i.tag + " " + i.alias
 }
 
 type model struct {
-    list list.Model
+	list   list.Model
+	input  textinput.Model
+	prompt string // "", "tag", "export", "delete", "view", "new"
+	form   snippetForm
+	sep    string // separator used when copying/exporting marked snippets
+
+	view       tuiView   // filter currently applied to the list
+	views      []tuiView // sidebar entries
+	sidebar    bool      // sidebar visible
+	sideFocus  bool      // keys go to the sidebar instead of the list
+	sideCursor int
+
+	txid int           // last DB transaction seen, to detect external writes
+	poll time.Duration // how often to check the DB for external writes
+
+	keys     keyMap
+	help     help.Model
+	showHelp bool // full key help overlay
+}
+
+// ------------------ NEW SNIPPET FORM ------------------
+
+// snippetForm collects a new snippet inside the TUI.
+type snippetForm struct {
+	text  textarea.Model
+	tag   textinput.Model
+	alias textinput.Model
+	focus int // 0 text, 1 tag, 2 alias
+	err   string
 }
 
-func newModel(snippets []item) model {
-    items := make([]list.Item, len(snippets))
-    for i, s := range snippets {
-        items[i] = s
-    }
+func newSnippetForm() snippetForm {
+	f := snippetForm{
+		text:  textarea.New(),
+		tag:   textinput.New(),
+		alias: textinput.New(),
+	}
+	f.text.Placeholder = "Snippet text (multi-line)"
+	f.text.SetWidth(70)
+	f.text.SetHeight(8)
+	f.tag.Placeholder = "tags, comma separated"
+	f.tag.Prompt = "🏷 "
+	f.alias.Placeholder = "alias"
+	f.alias.Prompt = "📖 "
+	f.text.Focus()
+	return f
+}
 
-    l := list.New(items, list.NewDefaultDelegate(), 80, 20)
-    l.Title = "📋 grb - Smart Clipboard Manager"
-    l.SetShowStatusBar(false)
-    l.SetShowHelp(false) // we'll use footer
+func (f *snippetForm) setFocus(n int) {
+	f.focus = (n + 3) % 3
+	f.text.Blur()
+	f.tag.Blur()
+	f.alias.Blur()
+	switch f.focus {
+	case 0:
+		f.text.Focus()
+	case 1:
+		f.tag.Focus()
+	case 2:
+		f.alias.Focus()
+	}
+}
 
-    return model{list: l}
+func (f snippetForm) view() string {
+	var b strings.Builder
+	b.WriteString(theme.accent.Sprint("📝 New snippet") + "\n\n")
+	b.WriteString(f.text.View() + "\n\n")
+	b.WriteString(f.tag.View() + "\n")
+	b.WriteString(f.alias.View() + "\n\n")
+	if f.err != "" {
+		b.WriteString(theme.danger.Sprint("❌ "+f.err) + "\n")
+	}
+	b.WriteString(theme.accent.Sprint("tab next field") + " | " +
+		theme.success.Sprint("ctrl+s save") + " | " +
+		theme.highlight.Sprint("esc cancel"))
+	return b.String()
 }
 
-func (m model) Init() tea.Cmd { return nil }
+// updateForm handles keys while the new-snippet form is open.
+func (m model) updateForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := &m.form
+	switch msg.String() {
+	case "esc":
+		m.prompt = ""
+		return m, nil
+	case "tab":
+		f.setFocus(f.focus + 1)
+		return m, nil
+	case "shift+tab":
+		f.setFocus(f.focus - 1)
+		return m, nil
+	case "ctrl+s":
+		text := f.text.Value()
+		tag := strings.Join(splitTags(f.tag.Value()), ",")
+		alias := strings.TrimSpace(f.alias.Value())
+		if strings.TrimSpace(text) == "" {
+			f.err = "snippet text is empty"
+			f.setFocus(0)
+			return m, nil
+		}
+		if err := validateAlias(alias); err != nil {
+			f.err = err.Error()
+			f.setFocus(2)
+			return m, nil
+		}
+		if alias != "" && aliasTaken(alias) {
+			f.err = fmt.Sprintf("alias %q is already used", alias)
+			f.setFocus(2)
+			return m, nil
+		}
+		id, err := createSnippet(text, tag, alias, false)
+		if err != nil {
+			f.err = err.Error()
+			return m, nil
+		}
+		// Same as 'grb save': the new snippet is copied right away.
+		m.prompt = ""
+		m.reload()
+		if !autoCopy() {
+			return m, m.list.NewStatusMessage(theme.success.Sprintf("✅ Saved snippet [%s]", id))
+		}
+		if err := writeClipboard(text); err != nil {
+			return m, m.list.NewStatusMessage(theme.highlight.Sprintf("✅ Saved snippet [%s], but ❌ %v", id, err))
+		}
+		return m, m.list.NewStatusMessage(theme.success.Sprintf("✅ Saved snippet [%s]", id))
+	}
 
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-    switch msg := msg.(type) {
-    case tea.KeyMsg:
-        switch msg.String() {
-        case "enter":
-    if i, ok := m.list.SelectedItem().(item); ok {
-        if i.section == "header" {
-            return m, nil
-        }
-        clipboard.WriteAll(i.text)
-        m.list.NewStatusMessage(color.GreenString("✅ Copied: %s", i.text))
-        // do NOT quit, just keep browsing
-        return m, nil
-    }
-
-        case "q", "esc":
-            return m, tea.Quit
-        }
-    }
-    var cmd tea.Cmd
-    m.list, cmd = m.list.Update(msg)
-    return m, cmd
+	var cmd tea.Cmd
+	switch f.focus {
+	case 0:
+		f.text, cmd = f.text.Update(msg)
+	case 1:
+		f.tag, cmd = f.tag.Update(msg)
+	case 2:
+		f.alias, cmd = f.alias.Update(msg)
+	}
+	return m, cmd
 }
 
-func (m model) View() string {
-    return m.list.View() + "\n" +
-        color.CyanString("↑/↓ move") + " | " +
-        color.GreenString("Enter copy") + " | " +
-        color.YellowString("q quit")
-}
-
-func launchTUI() {
-    var pinned []item
-    var others []item
-
-    db.View(func(tx *bbolt.Tx) error {
-        b := tx.Bucket([]byte("snippets"))
-        c := b.Cursor()
-        for k, v := c.First(); k != nil; k, v = c.Next() {
-            fields := strings.Split(string(v), "|")
-            pinnedFlag := "false"
-            if len(fields) > 3 {
-                pinnedFlag = fields[3]
-            }
-
-            itm := item{
-                id:      string(k),
-                text:    fields[0],
-                tag:     fields[1],
-                alias:   fields[2],
-                pin:     pinnedFlag,
-                section: "snippet",
-            }
-
-            if pinnedFlag == "true" {
-                pinned = append(pinned, itm)
-            } else {
-                others = append(others, itm)
-            }
-        }
-        return nil
-    })
-
-    var snippets []item
-    if len(pinned) > 0 {
-        snippets = append(snippets, item{text: "📌 Pinned", section: "header"})
-        snippets = append(snippets, pinned...)
-    }
-    if len(others) > 0 {
-        snippets = append(snippets, item{text: "Others", section: "header"})
-        snippets = append(snippets, others...)
-    }
-
-    p := tea.NewProgram(newModel(snippets))
-    if err := p.Start(); err != nil {
-        fmt.Println("Error running TUI:", err)
-    }
+// saveFromClipboard stores the current clipboard contents as a snippet.
+func (m *model) saveFromClipboard() tea.Cmd {
+	text, err := readClipboard()
+	if err != nil {
+		return m.list.NewStatusMessage(theme.danger.Sprintf("❌ %v", err))
+	}
+	if strings.TrimSpace(text) == "" {
+		return m.list.NewStatusMessage(theme.highlight.Sprint("⚠ Clipboard is empty"))
+	}
+	id, err := createSnippet(text, "", "", false)
+	if err != nil {
+		return m.list.NewStatusMessage(theme.danger.Sprintf("❌ %v", err))
+	}
+	m.reload()
+	return m.list.NewStatusMessage(theme.success.Sprintf("✅ Saved clipboard as snippet [%s]", id))
 }
 
-// ------------------ SAVE SNIPPET ------------------
-func saveSnippet(text, tag, alias string) {
-    var newID string
-
-    err := db.Update(func(tx *bbolt.Tx) error {
-        b := tx.Bucket([]byte("snippets"))
-        id, _ := b.NextSequence()
-        newID = fmt.Sprintf("%d", id)
-
-        val := fmt.Sprintf("%s|%s|%s|%t|%d|%d",
-            text, tag, alias, false, 0, time.Now().Unix())
-
-        return b.Put([]byte(newID), []byte(val))
-    })
-    if err != nil {
-        log.Fatal(err)
-    }
-
-    // Copy immediately
-    clipboard.WriteAll(text)
-
-    // Colors
-    cyan := color.New(color.FgCyan).SprintFunc()
-    yellow := color.New(color.FgYellow).SprintFunc()
-    magenta := color.New(color.FgMagenta).SprintFunc()
-    green := color.New(color.FgGreen).SprintFunc()
-
-    // Polished output
-    fmt.Printf("%s Saved snippet [%s]\n", green("✅"), cyan(newID))
-    
-    // Use custom table formatting
-    printSnippetTable([][]string{
-        {cyan(newID), text, magenta(tag), yellow(alias)},
-    })
-    
-    fmt.Println("📋 Copied to clipboard!")
-    fmt.Println("💡 Tip: Run 'grb list' to view snippets")
+// ------------------ KEYS ------------------
+
+// keyMap holds the TUI bindings. Each can be rebound in the [keys] section
+// of config.toml, e.g. delete = ["d", "delete"].
+type keyMap struct {
+	Copy       key.Binding
+	Mark       key.Binding
+	Delete     key.Binding
+	Pin        key.Binding
+	Tag        key.Binding
+	Export     key.Binding
+	CopyMarked key.Binding
+	Views      key.Binding
+	Focus      key.Binding
+	SaveView   key.Binding
+	New        key.Binding
+	Clipboard  key.Binding
+	Help       key.Binding
+	Quit       key.Binding
 }
 
-// ------------------ LIST SNIPPETS ------------------
+// defaultKeys lists the keys and help text for every configurable action.
+var defaultKeys = map[string]struct {
+	keys []string
+	desc string
+}{
+	"copy":        {[]string{"enter"}, "copy"},
+	"mark":        {[]string{"space"}, "mark"},
+	"delete":      {[]string{"x"}, "delete marked"},
+	"pin":         {[]string{"p"}, "pin/unpin marked"},
+	"tag":         {[]string{"t"}, "tag marked"},
+	"export":      {[]string{"e"}, "export marked"},
+	"copy_marked": {[]string{"y"}, "copy marked"},
+	"views":       {[]string{"v"}, "views"},
+	"focus":       {[]string{"tab"}, "switch focus"},
+	"save_view":   {[]string{"S"}, "save view"},
+	"new":         {[]string{"n"}, "new snippet"},
+	"clipboard":   {[]string{"c"}, "save clipboard"},
+	"help":        {[]string{"?"}, "help"},
+	"quit":        {[]string{"q", "esc"}, "quit"},
+}
 
-func listSnippets() {
-    total := 0
-    pinnedRows := [][]string{}
-    otherRows := [][]string{}
-
-    db.View(func(tx *bbolt.Tx) error {
-        b := tx.Bucket([]byte("snippets"))
-        c := b.Cursor()
-
-        for k, v := c.First(); k != nil; k, v = c.Next() {
-            total++
-            fields := strings.Split(string(v), "|")
-
-            id := string(k)
-            text := fields[0]
-            tag := "-"
-            alias := "-"
-            pinnedFlag := "false"
-
-            if len(fields) > 1 && fields[1] != "" {
-                tag = fields[1]
-            }
-            if len(fields) > 2 && fields[2] != "" {
-                alias = fields[2]
-            }
-            if len(fields) > 3 {
-                pinnedFlag = fields[3]
-            }
-
-            cyan := color.New(color.FgCyan).SprintFunc()
-            yellow := color.New(color.FgYellow).SprintFunc()
-            magenta := color.New(color.FgMagenta).SprintFunc()
-
-            row := []string{cyan(id), text, magenta(tag), yellow(alias)}
-
-            if pinnedFlag == "true" {
-                pinnedRows = append(pinnedRows, row)
-            } else {
-                otherRows = append(otherRows, row)
-            }
-        }
-        return nil
-    })
-
-    cyan := color.New(color.FgCyan).SprintFunc()
-    yellow := color.New(color.FgYellow).SprintFunc()
-
-    fmt.Println("─────────────────────────────────────────────")
-    fmt.Printf("%s (total: %d)\n", cyan("📋 Saved Snippets"), total)
-    fmt.Println("─────────────────────────────────────────────")
-
-    // Pinned section
-    if len(pinnedRows) > 0 {
-        fmt.Println(yellow("📌 Pinned"))
-        printSnippetTable(pinnedRows)
-        fmt.Println()
-    }
-
-    // Others section
-    if len(otherRows) > 0 {
-        fmt.Println(cyan("Others"))
-        printSnippetTable(otherRows)
-        fmt.Println()
-    }
-
-    if total == 0 {
-        color.Yellow("⚠ No snippets found.")
-        fmt.Println("💡 Tip: Use 'grb save \"text\"' to create your first snippet")
-    } else {
-        fmt.Println("💡 Tip: Use 'grb search <word>' to filter, or 'grb tui' for interactive mode.")
-    }
+func newKeyMap(overrides map[string][]string) keyMap {
+	bind := func(action string) key.Binding {
+		d := defaultKeys[action]
+		keys := d.keys
+		if o, ok := overrides[action]; ok && len(o) > 0 {
+			keys = o
+		}
+		// Bubble Tea reports the space bar as " ".
+		match := make([]string, len(keys))
+		for n, k := range keys {
+			match[n] = k
+			if k == "space" {
+				match[n] = " "
+			}
+		}
+		return key.NewBinding(key.WithKeys(match...), key.WithHelp(strings.Join(keys, "/"), d.desc))
+	}
+	return keyMap{
+		Copy:       bind("copy"),
+		Mark:       bind("mark"),
+		Delete:     bind("delete"),
+		Pin:        bind("pin"),
+		Tag:        bind("tag"),
+		Export:     bind("export"),
+		CopyMarked: bind("copy_marked"),
+		Views:      bind("views"),
+		Focus:      bind("focus"),
+		SaveView:   bind("save_view"),
+		New:        bind("new"),
+		Clipboard:  bind("clipboard"),
+		Help:       bind("help"),
+		Quit:       bind("quit"),
+	}
 }
 
-// ------------------ SEARCH ------------------
+func (k keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Copy, k.Mark, k.Views, k.Help, k.Quit}
+}
+
+func (k keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Copy, k.Mark, k.Views, k.Focus, k.SaveView, k.Help, k.Quit},
+		{k.New, k.Clipboard},
+		{k.Delete, k.Pin, k.Tag, k.Export, k.CopyMarked},
+	}
+}
 
-func searchSnippets(query string) {
-    resultsPinned := [][]string{}
-    resultsOthers := [][]string{}
-
-    db.View(func(tx *bbolt.Tx) error {
-        b := tx.Bucket([]byte("snippets"))
-        c := b.Cursor()
-
-        for k, v := c.First(); k != nil; k, v = c.Next() {
-            fields := strings.Split(string(v), "|")
-
-            id := string(k)
-            text := fields[0]
-            tag := "-"
-            alias := "-"
-            pinnedFlag := "false"
-
-            if len(fields) > 1 && fields[1] != "" {
-                tag = fields[1]
-            }
-            if len(fields) > 2 && fields[2] != "" {
-                alias = fields[2]
-            }
-            if len(fields) > 3 {
-                pinnedFlag = fields[3]
-            }
-
-            // Search match
-            if strings.Contains(strings.ToLower(text), strings.ToLower(query)) ||
-                strings.Contains(strings.ToLower(tag), strings.ToLower(query)) ||
-                strings.Contains(strings.ToLower(alias), strings.ToLower(query)) {
-
-                cyan := color.New(color.FgCyan).SprintFunc()
-                yellow := color.New(color.FgYellow).SprintFunc()
-                magenta := color.New(color.FgMagenta).SprintFunc()
-
-                row := []string{cyan(id), text, magenta(tag), yellow(alias)}
-                if pinnedFlag == "true" {
-                    resultsPinned = append(resultsPinned, row)
-                } else {
-                    resultsOthers = append(resultsOthers, row)
-                }
-            }
-        }
-        return nil
-    })
-
-    cyan := color.New(color.FgCyan).SprintFunc()
-    yellow := color.New(color.FgYellow).SprintFunc()
-
-    if len(resultsPinned)+len(resultsOthers) == 0 {
-        color.Yellow("⚠ No snippets found for \"%s\"", query)
-        fmt.Println("💡 Tip: Use 'grb list' to see all snippets")
-        return
-    }
-
-    fmt.Println("─────────────────────────────────────────────")
-    fmt.Printf("%s \"%s\"\n", cyan("🔍 Search Results for:"), query)
-    fmt.Println("─────────────────────────────────────────────")
-
-    // Pinned
-    if len(resultsPinned) > 0 {
-        fmt.Println(yellow("📌 Pinned"))
-        printSnippetTable(resultsPinned)
-        fmt.Println()
-    }
-
-    // Others
-    if len(resultsOthers) > 0 {
-        fmt.Println(cyan("Others"))
-        printSnippetTable(resultsOthers)
-        fmt.Println()
-    }
-
-    fmt.Println("💡 Tip: Use 'grb copy <id|alias>' to reuse a snippet")
+// hint renders a binding as "key desc" for the footer, colored by c.
+func hint(c *color.Color, b key.Binding) string {
+	h := b.Help().Key + " " + b.Help().Desc
+	if c == nil {
+		return h
+	}
+	return c.Sprint(h)
 }
 
-// ------------------ COPY ------------------
+// dbPollMsg asks the TUI to check the DB for external changes.
+type dbPollMsg struct{}
 
-func copySnippet(idOrAlias string) {
-    found := false
-
-    db.Update(func(tx *bbolt.Tx) error {
-        b := tx.Bucket([]byte("snippets"))
-        c := b.Cursor()
-
-        for k, v := c.First(); k != nil; k, v = c.Next() {
-            fields := strings.Split(string(v), "|")
-
-            id := string(k)
-            text := fields[0]
-            tag := "-"
-            alias := "-"
-            useCount := "0"
-
-            if len(fields) > 1 && fields[1] != "" {
-                tag = fields[1]
-            }
-            if len(fields) > 2 && fields[2] != "" {
-                alias = fields[2]
-            }
-            if len(fields) > 4 {
-                useCount = fields[4]
-            }
-
-            if id == idOrAlias || alias == idOrAlias {
-                // Copy to clipboard
-                clipboard.WriteAll(text)
-                found = true
-
-                // Increment usage count
-                count := 0
-                fmt.Sscanf(useCount, "%d", &count)
-                count++
-                newVal := fmt.Sprintf("%s|%s|%s|%s|%d|%d",
-                    text, tag, alias, fields[3], count, time.Now().Unix())
-                b.Put(k, []byte(newVal))
-
-                // Polished output
-                cyan := color.New(color.FgCyan).SprintFunc()
-                yellow := color.New(color.FgYellow).SprintFunc()
-                magenta := color.New(color.FgMagenta).SprintFunc()
-                green := color.New(color.FgGreen).SprintFunc()
-
-                fmt.Println(green("✅ Copied snippet [" + id + "]"))
-                
-                printSnippetTable([][]string{
-                    {cyan(id), text, magenta(tag), yellow(alias)},
-                })
-                
-                fmt.Println("💡 Tip: Paste it anywhere with Ctrl+V")
-                return nil
-            }
-        }
-        return nil
-    })
-
-    if !found {
-        color.Yellow("⚠ Snippet not found for \"%s\"", idOrAlias)
-        fmt.Println("💡 Tip: Run 'grb list' to see available snippets")
-    }
+// dbChangedMsg is sent when the daemon reports a new capture.
+type dbChangedMsg struct{}
+
+func pollDB(every time.Duration) tea.Cmd {
+	return tea.Tick(every, func(time.Time) tea.Msg { return dbPollMsg{} })
 }
 
-// ------------------ PIN TOGGLE ------------------
+// currentTxID returns the ID of the last committed write transaction.
+func currentTxID() int {
+	id := 0
+	db.View(func(tx *bbolt.Tx) error {
+		id = tx.ID()
+		return nil
+	})
+	return id
+}
+
+func newModel(snippets []item, sep string, poll time.Duration) model {
+	items := make([]list.Item, len(snippets))
+	for i, s := range snippets {
+		items[i] = s
+	}
 
-func pinSnippet(idOrAlias string) {
-    found := false
-
-    db.Update(func(tx *bbolt.Tx) error {
-        b := tx.Bucket([]byte("snippets"))
-        c := b.Cursor()
-
-        for k, v := c.First(); k != nil; k, v = c.Next() {
-            fields := strings.Split(string(v), "|")
-
-            id := string(k)
-            text := fields[0]
-            tag := "-"
-            alias := "-"
-            pinnedFlag := "false"
-
-            if len(fields) > 1 && fields[1] != "" {
-                tag = fields[1]
-            }
-            if len(fields) > 2 && fields[2] != "" {
-                alias = fields[2]
-            }
-            if len(fields) > 3 {
-                pinnedFlag = fields[3]
-            }
-
-            if id == idOrAlias || alias == idOrAlias {
-                found = true
-
-                // Toggle pin state
-                newPinned := "true"
-                action := "📌 Snippet pinned"
-                if pinnedFlag == "true" {
-                    newPinned = "false"
-                    action = "📍 Snippet unpinned"
-                }
-
-                // Save updated snippet
-                newVal := fmt.Sprintf("%s|%s|%s|%s|%s|%d",
-                    text,
-                    tag,
-                    alias,
-                    newPinned,
-                    fields[4],
-                    time.Now().Unix(),
-                )
-                b.Put(k, []byte(newVal))
-
-                // Polished output
-                cyan := color.New(color.FgCyan).SprintFunc()
-                yellow := color.New(color.FgYellow).SprintFunc()
-                magenta := color.New(color.FgMagenta).SprintFunc()
-
-                fmt.Printf("%s [%s]\n", action, cyan(id))
-                
-                printSnippetTable([][]string{
-                    {cyan(id), text, magenta(tag), yellow(alias)},
-                })
-
-                if newPinned == "true" {
-                    fmt.Println("💡 Tip: Run 'grb list' to see pinned snippets at the top")
-                } else {
-                    fmt.Println("💡 Tip: Run 'grb list' to see all snippets")
-                }
-
-                return nil
-            }
-        }
-        return nil
-    })
-
-    if !found {
-        color.Yellow("⚠ Snippet not found for \"%s\"", idOrAlias)
-        fmt.Println("💡 Tip: Run 'grb list' to see available snippets")
-    }
+	l := list.New(items, list.NewDefaultDelegate(), 80, 20)
+	l.Title = "📋 grb - Smart Clipboard Manager"
+	l.SetShowStatusBar(false)
+	l.SetShowHelp(false) // we'll use footer
+
+	return model{
+		list:  l,
+		input: textinput.New(),
+		sep:   sep,
+		view:  builtinViews[0],
+		txid:  currentTxID(),
+		poll:  poll,
+		keys:  newKeyMap(cfg.Keys),
+		help:  help.New(),
+	}
 }
 
-// ------------------ UPDATE ALIAS ------------------
-func updateAlias(idOrAlias, newAlias string) {
-    updated := false
-
-    db.Update(func(tx *bbolt.Tx) error {
-        b := tx.Bucket([]byte("snippets"))
-        c := b.Cursor()
-
-        for k, v := c.First(); k != nil; k, v = c.Next() {
-            fields := strings.Split(string(v), "|")
-            id := string(k)
-            alias := fields[2]
-
-            if id == idOrAlias || alias == idOrAlias {
-                fields[2] = newAlias
-                newVal := strings.Join(fields, "|")
-                b.Put(k, []byte(newVal))
-                updated = true
-
-                cyan := color.New(color.FgCyan).SprintFunc()
-                yellow := color.New(color.FgYellow).SprintFunc()
-                magenta := color.New(color.FgMagenta).SprintFunc()
-                green := color.New(color.FgGreen).SprintFunc()
-
-                fmt.Printf("%s Updated alias for snippet [%s]\n", green("✅"), cyan(id))
-                
-                printSnippetTable([][]string{
-                    {cyan(id), fields[0], magenta(fields[1]), yellow(fields[2])},
-                })
-                
-                fmt.Println("💡 Tip: Run 'grb list' to confirm changes")
-                break
-            }
-        }
-        return nil
-    })
-
-    if !updated {
-        color.Yellow("⚠ Snippet not found for \"%s\"", idOrAlias)
-        fmt.Println("💡 Tip: Run 'grb list' to see available snippets")
-    }
+func (m model) Init() tea.Cmd { return pollDB(m.poll) }
+
+// marked returns the snippets currently marked for a bulk action.
+func (m model) marked() []item {
+	var out []item
+	for _, li := range m.list.Items() {
+		if i, ok := li.(item); ok && i.marked {
+			out = append(out, i)
+		}
+	}
+	return out
 }
 
-// ------------------ DELETE ------------------
+func markedRefs(items []item) []ref {
+	refs := make([]ref, len(items))
+	for n, i := range items {
+		refs[n] = ref{i.vault, i.id}
+	}
+	return refs
+}
 
-func deleteSnippet(idOrAlias string) {
-	db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket([]byte("snippets"))
-		c := b.Cursor()
+// reload refreshes the list from the DB in place: marks on snippets that
+// still exist are kept so bulk actions can be chained, the typed filter is
+// re-applied and the cursor stays on the same snippet.
+func (m *model) reload() {
+	keep := map[ref]bool{}
+	for _, i := range m.marked() {
+		keep[ref{i.vault, i.id}] = true
+	}
+	selected, _ := m.list.SelectedItem().(item)
+	index := m.list.Index()
+
+	snippets := loadItems(m.view)
+	items := make([]list.Item, len(snippets))
+	for i, s := range snippets {
+		s.marked = s.section != "header" && keep[ref{s.vault, s.id}]
+		items[i] = s
+	}
+	// Run the filter synchronously so the cursor can be restored below.
+	if cmd := m.list.SetItems(items); cmd != nil {
+		m.list, _ = m.list.Update(cmd())
+	}
+	m.txid = currentTxID()
 
-		for k, v := c.First(); k != nil; k, v = c.Next() {
-			fields := strings.Split(string(v), "|")
-			alias := fields[2]
+	visible := m.list.VisibleItems()
+	for n, li := range visible {
+		if i := li.(item); i.vault == selected.vault && i.id == selected.id && i.text == selected.text {
+			m.list.Select(n)
+			return
+		}
+	}
+	if index >= len(visible) {
+		index = len(visible) - 1
+	}
+	if index >= 0 {
+		m.list.Select(index)
+	}
+}
 
-			if string(k) == idOrAlias || alias == idOrAlias {
-				b.Delete(k)
-				color.Red("🗑 Snippet deleted!")
-				return nil
+func (m *model) startPrompt(kind, placeholder string) tea.Cmd {
+	m.prompt = kind
+	m.input.Reset()
+	m.input.Placeholder = placeholder
+	return m.input.Focus()
+}
+
+// updatePrompt handles keys while a bulk-action prompt is open.
+func (m model) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.prompt == "new" {
+		return m.updateForm(msg)
+	}
+	// Only y deletes; every other key, Enter included, leaves the prompt
+	// open or cancels it.
+	if m.prompt == "delete" {
+		switch msg.String() {
+		case "y", "Y":
+			m.prompt = ""
+			return m, m.runBulk("delete", "")
+		case "n", "N", "esc":
+			m.prompt = ""
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "esc":
+		m.prompt = ""
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		kind, value := m.prompt, strings.TrimSpace(m.input.Value())
+		m.prompt = ""
+		m.input.Blur()
+		if kind == "view" {
+			return m, m.saveCurrentView(value)
+		}
+		return m, m.runBulk(kind, value)
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// runBulk applies a bulk action to the marked snippets.
+func (m *model) runBulk(kind, value string) tea.Cmd {
+	marked := m.marked()
+	refs := markedRefs(marked)
+	var status string
+	var err error
+
+	if kind == "delete" || kind == "pin" || kind == "tag" {
+		for _, i := range marked {
+			if i.section == "project" {
+				return m.list.NewStatusMessage(theme.highlight.Sprint("⚠ Project snippets are read-only; edit the project's .grb.yaml"))
 			}
 		}
-		color.Yellow("⚠ Snippet not found!")
-		return nil
-	})
+	}
+
+	switch kind {
+	case "delete":
+		var n int
+		n, err = bulkDelete(refs)
+		status = fmt.Sprintf("🗑 Deleted %d snippet(s)", n)
+	case "pin":
+		var pinned bool
+		pinned, err = bulkTogglePin(refs)
+		status = fmt.Sprintf("📍 Unpinned %d snippet(s)", len(refs))
+		if pinned {
+			status = fmt.Sprintf("📌 Pinned %d snippet(s)", len(refs))
+		}
+	case "tag":
+		if value == "" {
+			return nil
+		}
+		err = bulkAddTag(refs, value)
+		status = fmt.Sprintf("🏷 Tagged %d snippet(s) with %s", len(refs), value)
+	case "export":
+		if value == "" {
+			return nil
+		}
+		// Like 'grb export', leave secrets out of the file.
+		var plain []item
+		for _, i := range marked {
+			if !i.secret {
+				plain = append(plain, i)
+			}
+		}
+		if len(plain) == 0 {
+			return m.list.NewStatusMessage(theme.highlight.Sprint("⚠ Only secret snippets are marked; nothing to export"))
+		}
+		err = os.WriteFile(value, []byte(joinItems(plain, m.sep)), 0600)
+		status = fmt.Sprintf("💾 Exported %d snippet(s) to %s", len(plain), value)
+		if skipped := len(marked) - len(plain); skipped > 0 {
+			status += fmt.Sprintf(" (%d secret one(s) skipped)", skipped)
+		}
+		if err == nil {
+			return m.list.NewStatusMessage(theme.success.Sprint(status))
+		}
+	case "copy":
+		secret := false
+		var used []ref
+		for _, i := range marked {
+			secret = secret || i.secret
+			if i.section == "snippet" {
+				used = append(used, ref{i.vault, i.id})
+			}
+		}
+		err = copyText(joinItems(marked, m.sep), secret)
+		status = fmt.Sprintf("✅ Copied %d snippet(s)", len(refs))
+		if err == nil {
+			logCopies(used)
+			return m.list.NewStatusMessage(theme.success.Sprint(status))
+		}
+	}
+
+	if err != nil {
+		return m.list.NewStatusMessage(theme.danger.Sprintf("❌ %v", err))
+	}
+	m.reload()
+	return m.list.NewStatusMessage(theme.success.Sprint(status))
 }
 
-// ------------------ CLEAR ------------------
+// unescapeSeparator lets --sep accept `\n` and `\t` escapes as typed in a shell.
+func unescapeSeparator(sep string) string {
+	return strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(sep)
+}
 
-func clearSnippets(all bool, tag string, unpinned bool) {
-	db.Update(func(tx *bbolt.Tx) error {
-		b := tx.Bucket([]byte("snippets"))
-		c := b.Cursor()
-		deleted := 0
+func joinItems(items []item, sep string) string {
+	texts := make([]string, len(items))
+	for n, i := range items {
+		texts[n] = i.text
+	}
+	return strings.Join(texts, sep)
+}
 
-		for k, v := c.First(); k != nil; k, v = c.Next() {
-			fields := strings.Split(string(v), "|")
-			text, t, alias, pinned := fields[0], fields[1], fields[2], fields[3]
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg.(type) {
+	case tea.KeyMsg, dbPollMsg, dbChangedMsg:
+		// The DB is only held while handling a message so the daemon can
+		// write captures in between.
+		if err := acquireDB(); err != nil {
+			if _, ok := msg.(dbPollMsg); ok {
+				return m, pollDB(m.poll)
+			}
+			return m, m.list.NewStatusMessage(theme.danger.Sprintf("❌ %v", err))
+		}
+		defer releaseDB()
+	}
+	return m.update(msg)
+}
 
-			if all || (tag != "" && t == tag) || (unpinned && pinned == "false") {
-				b.Delete(k)
-				color.Red("🗑 Deleted [%s] %s (%s) %s", string(k), text, t, alias)
-				deleted++
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case dbPollMsg:
+		if currentTxID() != m.txid {
+			m.reload()
+		}
+		return m, pollDB(m.poll)
+	case dbChangedMsg:
+		m.reload()
+		return m, nil
+	case tea.KeyMsg:
+		if m.prompt != "" {
+			return m.updatePrompt(msg)
+		}
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		if m.showHelp {
+			if key.Matches(msg, m.keys.Help, m.keys.Quit) {
+				m.showHelp = false
 			}
+			return m, nil
 		}
+		if m.sideFocus {
+			return m.updateSidebar(msg)
+		}
+		switch {
+		case key.Matches(msg, m.keys.Copy):
+			if i, ok := m.list.SelectedItem().(item); ok {
+				if i.section == "header" {
+					return m, nil
+				}
+				if err := copyText(i.text, i.secret); err != nil {
+					return m, m.list.NewStatusMessage(theme.danger.Sprintf("❌ %v", err))
+				}
+				if i.section == "snippet" {
+					logCopies([]ref{{i.vault, i.id}})
+				}
+				// do NOT quit, just keep browsing
+				if i.secret {
+					return m, m.list.NewStatusMessage(theme.success.Sprint("✅ Copied secret"))
+				}
+				return m, m.list.NewStatusMessage(theme.success.Sprintf("✅ Copied: %s", oneLine(i.text)))
+			}
 
-		if deleted == 0 {
-			color.Yellow("⚠ No matching snippets found.")
-		} else {
-			color.Green("✅ %d snippet(s) deleted.", deleted)
+		case key.Matches(msg, m.keys.Mark):
+			if i, ok := m.list.SelectedItem().(item); ok && i.section != "header" {
+				i.marked = !i.marked
+				cmd := m.list.SetItem(m.list.GlobalIndex(), i)
+				m.list.CursorDown()
+				return m, cmd
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.Delete, m.keys.Pin, m.keys.Tag, m.keys.Export, m.keys.CopyMarked):
+			n := len(m.marked())
+			if n == 0 {
+				return m, m.list.NewStatusMessage(theme.highlight.Sprintf("⚠ Mark snippets with %s first", m.keys.Mark.Help().Key))
+			}
+			switch {
+			case key.Matches(msg, m.keys.Delete):
+				m.prompt = "delete"
+				return m, nil
+			case key.Matches(msg, m.keys.Tag):
+				return m, m.startPrompt("tag", "tag to add")
+			case key.Matches(msg, m.keys.Export):
+				return m, m.startPrompt("export", "file to export to")
+			case key.Matches(msg, m.keys.Pin):
+				return m, m.runBulk("pin", "")
+			default:
+				return m, m.runBulk("copy", "")
+			}
+
+		case key.Matches(msg, m.keys.Views):
+			m.sidebar = !m.sidebar
+			m.sideFocus = m.sidebar
+			if m.sidebar {
+				m.views = sidebarViews(loadSnippets())
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.Focus) && m.sidebar:
+			m.sideFocus = true
+			return m, nil
+
+		case key.Matches(msg, m.keys.New):
+			m.prompt = "new"
+			m.form = newSnippetForm()
+			return m, textarea.Blink
+
+		case key.Matches(msg, m.keys.Clipboard):
+			return m, m.saveFromClipboard()
+
+		case key.Matches(msg, m.keys.SaveView):
+			return m, m.startPrompt("view", "name for this view")
+
+		case key.Matches(msg, m.keys.Help):
+			m.showHelp = true
+			return m, nil
+
+		case key.Matches(msg, m.keys.Quit):
+			return m, tea.Quit
+		}
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// updateSidebar handles keys while the sidebar has focus.
+func (m model) updateSidebar(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.sideCursor > 0 {
+			m.sideCursor--
+		}
+	case "down", "j":
+		if m.sideCursor < len(m.views)-1 {
+			m.sideCursor++
+		}
+	case "enter":
+		if m.sideCursor < len(m.views) {
+			return m, m.applyView(m.views[m.sideCursor])
+		}
+	case "esc":
+		m.sidebar = false
+		m.sideFocus = false
+	case "ctrl+c":
+		return m, tea.Quit
+	default:
+		switch {
+		case key.Matches(msg, m.keys.Focus):
+			m.sideFocus = false
+		case key.Matches(msg, m.keys.Views):
+			m.sidebar = false
+			m.sideFocus = false
+		case key.Matches(msg, m.keys.Quit):
+			return m, tea.Quit
 		}
+	}
+	return m, nil
+}
+
+// applyView switches the list to v, clearing any typed filter.
+func (m *model) applyView(v tuiView) tea.Cmd {
+	m.view = v
+	m.list.ResetFilter()
+	m.list.Title = "📋 grb - Smart Clipboard Manager"
+	if v.kind != "all" {
+		m.list.Title += " · " + v.name
+	}
+	m.reload()
+	m.list.ResetSelected()
+	return nil
+}
+
+// saveCurrentView stores the active view plus any typed filter under name.
+func (m *model) saveCurrentView(name string) tea.Cmd {
+	if name == "" {
 		return nil
-	})
+	}
+	query := strings.TrimSpace(m.view.query + " " + m.list.FilterValue())
+	if err := saveView(name, m.view.tag, query); err != nil {
+		return m.list.NewStatusMessage(theme.danger.Sprintf("❌ %v", err))
+	}
+	if m.sidebar {
+		m.views = sidebarViews(loadSnippets())
+	}
+	return m.list.NewStatusMessage(theme.success.Sprintf("⭐ Saved view %s", name))
 }
 
-// ------------------ EDIT ------------------
+func (m model) sidebarView() string {
+	var b strings.Builder
+	b.WriteString(theme.accent.Sprint("Views") + "\n")
+	section := ""
+	for n, v := range m.views {
+		// Daemon captures is a tag view too, but a built-in one.
+		if v.kind == "tag" && n >= len(builtinViews) && section != "tags" {
+			section = "tags"
+			b.WriteString("\n" + theme.accent.Sprint("Tags") + "\n")
+		}
+		if v.kind == "saved" && section != "saved" {
+			section = "saved"
+			b.WriteString("\n" + theme.accent.Sprint("Saved") + "\n")
+		}
+		line := "  " + v.label
+		if n == m.sideCursor && m.sideFocus {
+			line = theme.success.Sprint("▶ " + v.label)
+		} else if v.name == m.view.name && v.kind == m.view.kind {
+			line = theme.highlight.Sprint("• " + v.label)
+		}
+		b.WriteString(line + "\n")
+	}
+	return lipgloss.NewStyle().Width(30).PaddingRight(2).Render(b.String())
+}
 
-func editSnippet(idOrAlias string) {
-    tmpFile := filepath.Join(os.TempDir(), "grb_edit.txt")
-
-    var key []byte
-    var original []string
-    var id string
-
-    // Find snippet
-    db.View(func(tx *bbolt.Tx) error {
-        b := tx.Bucket([]byte("snippets"))
-        c := b.Cursor()
-        for k, v := c.First(); k != nil; k, v = c.Next() {
-            fields := strings.Split(string(v), "|")
-            alias := "-"
-            if len(fields) > 2 && fields[2] != "" {
-                alias = fields[2]
-            }
-            if string(k) == idOrAlias || alias == idOrAlias {
-                key = append([]byte{}, k...)
-                id = string(k)
-                original = fields
-                os.WriteFile(tmpFile, []byte(fields[0]), 0644)
-            }
-        }
-        return nil
-    })
-
-    if key == nil {
-        color.Yellow("⚠ Snippet not found for \"%s\"", idOrAlias)
-        fmt.Println("💡 Tip: Run 'grb list' to see available snippets")
-        return
-    }
-
-    // Capture old text before editing
-    oldText := original[0]
-
-    // Open in default editor
-    var cmd *exec.Cmd
-    if runtime.GOOS == "windows" {
-        cmd = exec.Command("notepad", tmpFile)
-    } else {
-        editor := os.Getenv("EDITOR")
-        if editor == "" {
-            editor = "nano"
-        }
-        cmd = exec.Command(editor, tmpFile)
-    }
-    cmd.Stdin = os.Stdin
-    cmd.Stdout = os.Stdout
-    cmd.Stderr = os.Stderr
-    cmd.Run()
-
-    // Read back and update DB
-    edited, _ := os.ReadFile(tmpFile)
-    newText := string(edited)
-
-    db.Update(func(tx *bbolt.Tx) error {
-        b := tx.Bucket([]byte("snippets"))
-        original[0] = newText
-        newVal := strings.Join(original, "|")
-        return b.Put(key, []byte(newVal))
-    })
-
-    // Polished output
-    cyan := color.New(color.FgCyan).SprintFunc()
-    yellow := color.New(color.FgYellow).SprintFunc()
-    magenta := color.New(color.FgMagenta).SprintFunc()
-    green := color.New(color.FgGreen).SprintFunc()
-
-    fmt.Printf("%s Snippet [%s] updated\n", green("✅"), cyan(id))
-    
-    fmt.Println("Before")
-    fmt.Println("─────────────────────────────────────────────")
-    printSnippetTable([][]string{
-        {cyan(id), oldText, magenta(original[1]), yellow(original[2])},
-    })
-
-    fmt.Println("\nAfter")
-    fmt.Println("─────────────────────────────────────────────")
-    printSnippetTable([][]string{
-        {cyan(id), newText, magenta(original[1]), yellow(original[2])},
-    })
-
-    fmt.Println("💡 Tip: Run 'grb list' to confirm all snippets")
+func (m model) View() string {
+	switch m.prompt {
+	case "delete":
+		return m.list.View() + "\n" +
+			theme.danger.Sprintf("🗑 Delete %d marked snippet(s)? (y/n)", len(m.marked()))
+	case "tag", "export":
+		return m.list.View() + "\n" + m.input.View() + "  " +
+			theme.accent.Sprint("Enter confirm") + " | " + theme.highlight.Sprint("Esc cancel")
+	case "view":
+		return m.list.View() + "\n" + m.input.View() + "  " +
+			theme.accent.Sprint("Enter save") + " | " + theme.highlight.Sprint("Esc cancel")
+	}
+
+	if m.prompt == "new" {
+		return m.form.view()
+	}
+
+	if m.showHelp {
+		box := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(1, 2)
+		return theme.accent.Sprint("⌨ Keys") + "\n" +
+			box.Render(m.help.FullHelpView(m.keys.FullHelp())) + "\n" +
+			theme.highlight.Sprintf("%s close", m.keys.Help.Help().Key)
+	}
+
+	footer := theme.accent.Sprint("↑/↓ move") + " | " +
+		hint(theme.success, m.keys.Copy) + " | " +
+		hint(theme.label, m.keys.Mark) + " | " +
+		hint(theme.accent, m.keys.Views) + " | " +
+		hint(theme.accent, m.keys.New) + " | " +
+		hint(theme.accent, m.keys.Help) + " | " +
+		hint(theme.highlight, m.keys.Quit)
+	if m.sidebar {
+		footer += "\n" + hint(nil, m.keys.Focus) + " | Enter apply view | " + hint(nil, m.keys.SaveView)
+	}
+	if n := len(m.marked()); n > 0 {
+		footer += "\n" + theme.success.Sprintf("%d marked", n) + ": " +
+			hint(nil, m.keys.Delete) + " | " + hint(nil, m.keys.Pin) + " | " +
+			hint(nil, m.keys.Tag) + " | " + hint(nil, m.keys.Export) + " | " +
+			hint(nil, m.keys.CopyMarked)
+	}
+	body := m.list.View()
+	if m.sidebar {
+		body = lipgloss.JoinHorizontal(lipgloss.Top, m.sidebarView(), body)
+	}
+	return body + "\n" + footer
 }
 
-// ------------------ STATS ------------------
+// loadItems reads the snippets matching v, grouped under pinned/others
+// headers unless the view imposes its own ordering.
+func loadItems(v tuiView) []item {
+	var pinned []item
+	var others []item
+
+	matched := v.apply(loadSnippets())
+	for _, s := range matched {
+		itm := item{
+			vault:   s.vault,
+			id:      s.id,
+			text:    s.text,
+			tag:     s.tag,
+			alias:   s.alias,
+			pin:     fmt.Sprintf("%t", s.pinned),
+			secret:  s.secret,
+			section: "snippet",
+		}
+
+		if s.pinned && !v.ordered() {
+			pinned = append(pinned, itm)
+		} else {
+			others = append(others, itm)
+		}
+	}
 
-func showStats() {
-    total := 0
-    tagCount := map[string]int{}
-    var topSnippet string
-    maxCount := 0
-    var topTag string
-    maxTagCount := 0
-
-    db.View(func(tx *bbolt.Tx) error {
-        b := tx.Bucket([]byte("snippets"))
-        c := b.Cursor()
-        for k, v := c.First(); k != nil; k, v = c.Next() {
-            fields := strings.Split(string(v), "|")
-            total++
-
-            tag := ""
-            count := 0
-
-            if len(fields) > 1 && fields[1] != "" {
-                tag = fields[1]
-            }
-            if len(fields) > 4 {
-                fmt.Sscanf(fields[4], "%d", &count)
-            }
-
-            if tag != "" {
-                tagCount[tag]++
-                if tagCount[tag] > maxTagCount {
-                    maxTagCount = tagCount[tag]
-                    topTag = tag
-                }
-            }
-            if count > maxCount {
-                maxCount = count
-                topSnippet = fmt.Sprintf("[%s] %s", string(k), fields[0])
-            }
-        }
-        return nil
-    })
-
-    cyan := color.New(color.FgCyan).SprintFunc()
-    green := color.New(color.FgGreen).SprintFunc()
-    yellow := color.New(color.FgYellow).SprintFunc()
-    red := color.New(color.FgRed).SprintFunc()
-
-    fmt.Println("─────────────────────────────────────────────")
-    fmt.Println(cyan("📊 grb Stats"))
-    fmt.Println("─────────────────────────────────────────────")
-    fmt.Printf("%-18s : %s\n", "Total snippets", green(fmt.Sprintf("%d", total)))
-    if topSnippet != "" {
-        fmt.Printf("%-18s : %s (%s)\n", "Most used", yellow(topSnippet), red(fmt.Sprintf("🔥 %d times", maxCount)))
-    }
-    if topTag != "" {
-        fmt.Printf("%-18s : 🏷 %s (%d snippets)\n", "Top tag", yellow(topTag), maxTagCount)
-    }
-
-    if len(tagCount) > 0 {
-        fmt.Println("─────────────────────────────────────────────")
-        fmt.Println(cyan("Tag Breakdown"))
-        fmt.Println("─────────────────────────────────────────────")
-        
-        // Simple table for tag breakdown
-        fmt.Println("┌──────────────────────┬───────┐")
-        fmt.Printf("│ %-20s │ %-5s │\n", "Tag", "Count")
-        fmt.Println("├──────────────────────┼───────┤")
-        
-        for t, c := range tagCount {
-            tagDisplay := yellow("🏷 " + t)
-            countDisplay := green(fmt.Sprintf("%d", c))
-            fmt.Printf("│ %s │ %s │\n",
-                padRight(tagDisplay, 20),
-                padRight(countDisplay, 5))
-        }
-        fmt.Println("└──────────────────────┴───────┘")
-    }
+	// Project snippets are listed after pinned ones in grouped views.
+	var project []item
+	if !v.ordered() && !showArchived {
+		for _, s := range projectSnippets() {
+			if v.matches(s) {
+				project = append(project, item{id: s.id, text: s.text, tag: s.tag, alias: s.alias, section: "project"})
+			}
+		}
+	}
+
+	var snippets []item
+	if len(pinned) > 0 {
+		snippets = append(snippets, item{text: "📌 Pinned", section: "header"})
+		snippets = append(snippets, pinned...)
+	}
+	if len(project) > 0 {
+		snippets = append(snippets, item{text: "📁 Project", section: "header"})
+		snippets = append(snippets, project...)
+	}
+	if len(others) > 0 {
+		header := "Others"
+		if v.ordered() {
+			header = v.name
+		}
+		snippets = append(snippets, item{text: header, section: "header"})
+		snippets = append(snippets, others...)
+	}
+	return snippets
 }
 
-// ------------------ DAEMON ------------------
+func launchTUI(sep string) error {
+	// With a daemon running, captures are pushed over its socket and the
+	// DB only needs an occasional check for edits made by other commands.
+	poll := 1 * time.Second
+	conn := dialDaemon()
+	if conn != nil {
+		poll = 5 * time.Second
+	}
+
+	m := newModel(loadItems(builtinViews[0]), sep, poll)
+	releaseDB()
+
+	p := tea.NewProgram(m)
+	if conn != nil {
+		go subscribeDaemon(conn, func(string) { p.Send(dbChangedMsg{}) })
+	}
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("running TUI: %w", err)
+	}
+	return nil
+}
+
+// ------------------ BULK ------------------
 
-func startDaemon() {
-    color.Yellow("📡 grb Daemon started. Watching clipboard...")
-    fmt.Println("─────────────────────────────────────────────")
-
-    last := ""
-
-    for {
-        text, _ := clipboard.ReadAll()
-        if text != "" && text != last {
-            var newID string
-
-            db.Update(func(tx *bbolt.Tx) error {
-                b := tx.Bucket([]byte("snippets"))
-                id, _ := b.NextSequence()
-                newID = fmt.Sprintf("%d", id)
-
-                val := fmt.Sprintf("%s|%s|%s|%t|%d|%d",
-                    text, "auto", "", false, 0, time.Now().Unix())
-
-                return b.Put([]byte(newID), []byte(val))
-            })
-
-            // Colors
-            cyan := color.New(color.FgCyan).SprintFunc()
-            yellow := color.New(color.FgYellow).SprintFunc()
-            magenta := color.New(color.FgMagenta).SprintFunc()
-            green := color.New(color.FgGreen).SprintFunc()
-
-            // Polished output
-            fmt.Printf("\n%s snippet [%s]\n", green("✅ Captured"), cyan(newID))
-            
-            printSnippetTable([][]string{
-                {cyan(newID), text, magenta("auto"), yellow("-")},
-            })
-            
-            fmt.Println("💡 Tip: Press Ctrl+C to stop daemon")
-
-            last = text
-        }
-        time.Sleep(1 * time.Second)
-    }
-}
\ No newline at end of file
+// ref names a snippet in a specific vault, so bulk actions also work in a
+// TUI showing every vault.
+type ref struct {
+	vault string
+	id    string
+}
+
+// bulkDelete removes every snippet in refs and reports how many existed.
+func bulkDelete(refs []ref) (int, error) {
+	deleted := 0
+	err := db.Update(func(tx *bbolt.Tx) error {
+		for _, r := range refs {
+			b := tx.Bucket(snippetsKey(r.vault))
+			if b == nil {
+				continue
+			}
+			v := b.Get([]byte(r.id))
+			if v == nil {
+				continue
+			}
+			if err := dropSnippet(tx, r.vault, parseSnippet([]byte(r.id), v)); err != nil {
+				return err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// bulkTogglePin pins all of refs, or unpins them if they are all pinned
+// already. It reports the resulting pin state.
+func bulkTogglePin(refs []ref) (bool, error) {
+	pin := false
+	err := db.Update(func(tx *bbolt.Tx) error {
+		var snippets []snippet
+		for _, r := range refs {
+			if b := tx.Bucket(snippetsKey(r.vault)); b != nil {
+				if v := b.Get([]byte(r.id)); v != nil {
+					s := parseSnippet([]byte(r.id), v)
+					s.vault = r.vault
+					if !s.pinned {
+						pin = true
+					}
+					snippets = append(snippets, s)
+				}
+			}
+		}
+		for _, s := range snippets {
+			s.pinned = pin
+			if err := putSnippet(tx.Bucket(snippetsKey(s.vault)), s.id, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return pin, err
+}
+
+// bulkAddTag adds tag to each snippet in refs.
+func bulkAddTag(refs []ref, tag string) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		for _, r := range refs {
+			b := tx.Bucket(snippetsKey(r.vault))
+			if b == nil {
+				continue
+			}
+			v := b.Get([]byte(r.id))
+			if v == nil {
+				continue
+			}
+			s := parseSnippet([]byte(r.id), v)
+			s.tag = addTag(s.tag, tag)
+			if err := putSnippet(b, r.id, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ------------------ VIEWS ------------------
+
+// tuiView is a named filter over the snippet list, picked from the TUI sidebar.
+type tuiView struct {
+	name  string
+	label string // text shown in the sidebar
+	kind  string // "all", "pinned", "recent", "used", "untagged", "tag", "saved"
+	tag   string
+	query string
+}
+
+// viewLimit caps how many snippets the Recent and Most used views show.
+const viewLimit = 20
+
+var builtinViews = []tuiView{
+	{name: "All", label: "All", kind: "all"},
+	{name: "📌 Pinned", label: "📌 Pinned", kind: "pinned"},
+	{name: "🕑 Recent", label: "🕑 Recent", kind: "recent"},
+	{name: "🔥 Most used", label: "🔥 Most used", kind: "used"},
+	{name: "📡 Daemon captures", label: "📡 Daemon captures", kind: "tag", tag: "auto"},
+	{name: "Untagged", label: "Untagged", kind: "untagged"},
+}
+
+// ordered reports whether the view sorts snippets itself instead of
+// grouping pinned ones first.
+func (v tuiView) ordered() bool {
+	return v.kind == "recent" || v.kind == "used"
+}
+
+func (v tuiView) matches(s snippet) bool {
+	switch v.kind {
+	case "pinned":
+		if !s.pinned {
+			return false
+		}
+	case "used":
+		if s.useCount == 0 {
+			return false
+		}
+	case "untagged":
+		if len(splitTags(s.tag)) > 0 {
+			return false
+		}
+	}
+	if v.tag != "" && !hasTag(s.tag, v.tag) {
+		return false
+	}
+	for _, word := range strings.Fields(strings.ToLower(v.query)) {
+		if !strings.Contains(strings.ToLower(s.text+" "+s.tag+" "+s.alias), word) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply filters snippets through the view and sorts them if it is ordered.
+func (v tuiView) apply(snippets []snippet) []snippet {
+	var out []snippet
+	for _, s := range snippets {
+		if v.matches(s) {
+			out = append(out, s)
+		}
+	}
+	switch v.kind {
+	case "recent":
+		sort.SliceStable(out, func(i, j int) bool { return out[i].created > out[j].created })
+	case "used":
+		sort.SliceStable(out, func(i, j int) bool { return out[i].useCount > out[j].useCount })
+	}
+	if v.ordered() && len(out) > viewLimit {
+		out = out[:viewLimit]
+	}
+	return out
+}
+
+// hasTag reports whether the tag field contains t.
+func hasTag(tag, t string) bool {
+	for _, existing := range splitTags(tag) {
+		if existing == t {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSnippets reads every snippet in key order, vault by vault when
+// --all-vaults is set.
+func loadSnippets() []snippet {
+	var snippets []snippet
+	vaults := searchVaults()
+	db.View(func(tx *bbolt.Tx) error {
+		for _, name := range vaults {
+			err := tx.Bucket(snippetsKey(name)).ForEach(func(k, v []byte) error {
+				s := parseSnippet(k, v)
+				if s.archived != showArchived {
+					return nil
+				}
+				s.vault = name
+				snippets = append(snippets, s)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return snippets
+}
+
+// sidebarViews lists the built-in views, one view per tag with its count
+// (most used tags first), and the user's saved views.
+func sidebarViews(snippets []snippet) []tuiView {
+	views := append([]tuiView{}, builtinViews...)
+
+	counts := map[string]int{}
+	for _, s := range snippets {
+		for _, t := range splitTags(s.tag) {
+			counts[t]++
+		}
+	}
+	tags := make([]string, 0, len(counts))
+	for t := range counts {
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	for _, t := range tags {
+		views = append(views, tuiView{
+			name:  "🏷 " + t,
+			label: fmt.Sprintf("🏷 %s (%d)", t, counts[t]),
+			kind:  "tag",
+			tag:   t,
+		})
+	}
+
+	return append(views, loadSavedViews()...)
+}
+
+// DB schema (views bucket): name -> tag|query
+
+func loadSavedViews() []tuiView {
+	var views []tuiView
+	db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("views"))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			fields := strings.SplitN(string(v), "|", 2)
+			for len(fields) < 2 {
+				fields = append(fields, "")
+			}
+			views = append(views, tuiView{
+				name:  "⭐ " + string(k),
+				label: "⭐ " + string(k),
+				kind:  "saved",
+				tag:   fields[0],
+				query: fields[1],
+			})
+			return nil
+		})
+	})
+	return views
+}
+
+func saveView(name, tag, query string) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("views"))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(name), []byte(tag+"|"+query))
+	})
+}
+
+// deleteView removes a saved view and reports whether it existed.
+func deleteView(name string) (bool, error) {
+	found := false
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("views"))
+		if b == nil || b.Get([]byte(name)) == nil {
+			return nil
+		}
+		found = true
+		return b.Delete([]byte(name))
+	})
+	return found, err
+}
+
+// ------------------ SAVE SNIPPET ------------------
+
+// createSnippet stores a new snippet and returns its id. It is the single
+// write path for new snippets from the CLI and the TUI.
+func createSnippet(text, tag, alias string, secret bool) (string, error) {
+	var newID string
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(vault))
+		id, _ := b.NextSequence()
+		newID = fmt.Sprintf("%d", id)
+
+		if err := claimAlias(tx, vault, alias, newID); err != nil {
+			return err
+		}
+		s := snippet{text: text, tag: tag, alias: alias, secret: secret, created: time.Now().Unix()}
+		return putSnippet(b, newID, s)
+	})
+	return newID, err
+}
+
+// aliasTaken reports whether another snippet already uses alias.
+func aliasTaken(alias string) bool {
+	taken := false
+	db.View(func(tx *bbolt.Tx) error {
+		if idx := tx.Bucket(aliasesKey(vault)); idx != nil {
+			taken = idx.Get([]byte(alias)) != nil
+		}
+		return nil
+	})
+	return taken
+}
+
+// snippetInput returns the text to save from exactly one source: the
+// arguments, stdin ("-"), --file, --from-clipboard or --editor. Only the
+// arguments are joined with spaces; every other source is kept byte for byte.
+func snippetInput(cmd *cobra.Command, args []string) (string, error) {
+	file, _ := cmd.Flags().GetString("file")
+	fromClipboard, _ := cmd.Flags().GetBool("from-clipboard")
+	useEditor, _ := cmd.Flags().GetBool("editor")
+
+	stdin := len(args) == 1 && args[0] == "-"
+	sources := 0
+	for _, set := range []bool{len(args) > 0, file != "", fromClipboard, useEditor} {
+		if set {
+			sources++
+		}
+	}
+	if sources == 0 {
+		return "", usagef("Provide text to save (or -, --file, --from-clipboard, --editor)")
+	}
+	if sources > 1 {
+		return "", usagef("Use only one of: text, -, --file, --from-clipboard, --editor")
+	}
+
+	var text string
+	switch {
+	case stdin:
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		text = string(data)
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		text = string(data)
+	case fromClipboard:
+		var err error
+		if text, err = readClipboard(); err != nil {
+			return "", err
+		}
+	case useEditor:
+		tmpFile, err := editorTempFile("grb-new-*.txt", "")
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(tmpFile)
+		if err := runEditor(tmpFile); err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(tmpFile)
+		if err != nil {
+			return "", err
+		}
+		text = string(data)
+	default:
+		text = strings.Join(args, " ")
+	}
+
+	if strings.TrimSpace(text) == "" {
+		return "", usagef("Nothing to save: the snippet is empty")
+	}
+	return text, nil
+}
+
+// saveSnippet stores text and, if copyIt is set, copies it. A clipboard
+// failure is reported after the snippet has been saved.
+func saveSnippet(text, tag, alias string, secret, copyIt bool) error {
+	newID, err := createSnippet(text, tag, alias, secret)
+	if err != nil {
+		return err
+	}
+	s := snippet{id: newID, text: text, tag: tag, alias: alias, secret: secret}
+
+	// Copy immediately
+	var copyErr error
+	if copyIt {
+		copyErr = copyText(text, secret)
+	}
+
+	// Colors
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+	success := theme.success.SprintFunc()
+
+	// Polished output
+	fmt.Printf("%s Saved snippet [%s]\n", success("✅"), accent(newID))
+
+	// Use custom table formatting
+	printSnippetTable([][]string{
+		{accent(newID), s.shownText(), label(tag), highlight(alias)},
+	})
+
+	if copyErr != nil {
+		return copyErr
+	}
+	if copyIt {
+		fmt.Println("📋 Copied to clipboard!")
+		if secret && clearAfter() > 0 {
+			fmt.Printf("🔑 Clipboard clears in %s\n", clearAfter())
+		}
+	}
+	fmt.Println("💡 Tip: Run 'grb list' to view snippets")
+	return nil
+}
+
+// saveToProject is saveSnippet for 'grb save --project'.
+func saveToProject(text, tag, alias string, copyIt bool) error {
+	file, id, err := saveProjectSnippet(text, tag, alias)
+	if err != nil {
+		return err
+	}
+	var copyErr error
+	if copyIt {
+		copyErr = writeClipboard(text)
+	}
+
+	accent := theme.accent.SprintFunc()
+	success := theme.success.SprintFunc()
+
+	fmt.Printf("%s Saved project snippet [%s] to %s\n", success("✅"), accent(id), file)
+	printSnippetTable(projectRows([]snippet{{id: id, text: text, tag: tag, alias: alias}}))
+	if copyErr != nil {
+		return copyErr
+	}
+	if copyIt {
+		fmt.Println("📋 Copied to clipboard!")
+	}
+	fmt.Println("💡 Tip: Commit the file to share it with the repo")
+	return nil
+}
+
+// ------------------ LIST SNIPPETS ------------------
+
+func listSnippets() error {
+	total := 0
+	pinnedRows := [][]string{}
+	otherRows := [][]string{}
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(vault))
+		c := b.Cursor()
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			s := parseSnippet(k, v)
+			if s.archived != showArchived {
+				continue
+			}
+			total++
+
+			accent := theme.accent.SprintFunc()
+			highlight := theme.highlight.SprintFunc()
+			label := theme.label.SprintFunc()
+
+			row := []string{accent(s.id), s.shownText(), label(orDash(s.tag)), highlight(orDash(s.alias))}
+
+			if s.pinned {
+				pinnedRows = append(pinnedRows, row)
+			} else {
+				otherRows = append(otherRows, row)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Project snippets can't be archived.
+	var project []snippet
+	title := "📋 Saved Snippets"
+	if showArchived {
+		title = "📦 Archived Snippets"
+	} else {
+		project = projectSnippets()
+	}
+
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Printf("%s (total: %d)\n", accent(title), total)
+	fmt.Println("─────────────────────────────────────────────")
+
+	// Pinned section
+	if len(pinnedRows) > 0 {
+		fmt.Println(highlight("📌 Pinned"))
+		printSnippetTable(pinnedRows)
+		fmt.Println()
+	}
+
+	// Project section
+	if len(project) > 0 {
+		fmt.Println(accent("📁 Project"))
+		printSnippetTable(projectRows(project))
+		fmt.Println()
+	}
+
+	// Others section
+	if len(otherRows) > 0 {
+		fmt.Println(accent("Others"))
+		printSnippetTable(otherRows)
+		fmt.Println()
+	}
+
+	if total+len(project) == 0 && showArchived {
+		say(theme.highlight, "⚠ No archived snippets.")
+	} else if total+len(project) == 0 {
+		say(theme.highlight, "⚠ No snippets found.")
+		fmt.Println("💡 Tip: Use 'grb save \"text\"' to create your first snippet")
+	} else {
+		fmt.Println("💡 Tip: Use 'grb search <word>' to filter, or 'grb tui' for interactive mode.")
+	}
+	return nil
+}
+
+// ------------------ SEARCH ------------------
+
+// searchSnippets prints matches for query; no match is ErrNotFound so
+// scripts can test for it.
+func searchSnippets(query string) error {
+	resultsPinned := [][]string{}
+	resultsOthers := [][]string{}
+	vaults := searchVaults()
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		for _, name := range vaults {
+			c := tx.Bucket(snippetsKey(name)).Cursor()
+
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				s := parseSnippet(k, v)
+				if s.archived != showArchived {
+					continue
+				}
+
+				// Search match
+				if strings.Contains(strings.ToLower(s.text), strings.ToLower(query)) ||
+					strings.Contains(strings.ToLower(s.tag), strings.ToLower(query)) ||
+					strings.Contains(strings.ToLower(s.alias), strings.ToLower(query)) {
+
+					accent := theme.accent.SprintFunc()
+					highlight := theme.highlight.SprintFunc()
+					label := theme.label.SprintFunc()
+
+					row := []string{accent(s.id), s.shownText(), label(orDash(s.tag)), highlight(orDash(s.alias))}
+					if allVaults {
+						row = append(row, accent(name))
+					}
+					if s.pinned {
+						resultsPinned = append(resultsPinned, row)
+					} else {
+						resultsOthers = append(resultsOthers, row)
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var resultsProject []snippet
+	for _, s := range projectSnippets() {
+		if !showArchived && (tuiView{query: query}).matches(s) {
+			resultsProject = append(resultsProject, s)
+		}
+	}
+
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+
+	if len(resultsPinned)+len(resultsOthers)+len(resultsProject) == 0 {
+		return notFound(query)
+	}
+
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Printf("%s \"%s\"\n", accent("🔍 Search Results for:"), query)
+	fmt.Println("─────────────────────────────────────────────")
+
+	// Pinned
+	if len(resultsPinned) > 0 {
+		fmt.Println(highlight("📌 Pinned"))
+		printSnippetTable(resultsPinned)
+		fmt.Println()
+	}
+
+	// Project
+	if len(resultsProject) > 0 {
+		fmt.Println(accent("📁 Project"))
+		printSnippetTable(projectRows(resultsProject))
+		fmt.Println()
+	}
+
+	// Others
+	if len(resultsOthers) > 0 {
+		fmt.Println(accent("Others"))
+		printSnippetTable(resultsOthers)
+		fmt.Println()
+	}
+
+	fmt.Println("💡 Tip: Use 'grb copy <id|alias>' to reuse a snippet")
+	return nil
+}
+
+// ------------------ COPY ------------------
+
+func copySnippet(idOrAlias string) error {
+	var s snippet
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(vault))
+
+		var ok bool
+		if s, ok = findSnippet(tx, vault, idOrAlias); !ok {
+			return notFound(idOrAlias)
+		}
+
+		// Copy to clipboard
+		if err := copyText(s.text, s.secret); err != nil {
+			return err
+		}
+
+		// Increment usage count
+		s.useCount++
+		s.created = time.Now().Unix()
+		if err := logEvent(tx, eventCopy, vault, s.id); err != nil {
+			return err
+		}
+		return putSnippet(b, s.id, s)
+	})
+	if errors.Is(err, ErrNotFound) {
+		// Fall back to the project's read-only snippets.
+		var ok bool
+		if s, ok = findProjectSnippet(idOrAlias); ok {
+			err = writeClipboard(s.text)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Polished output
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+	success := theme.success.SprintFunc()
+
+	fmt.Println(success("✅ Copied snippet [" + s.id + "]"))
+
+	printSnippetTable([][]string{
+		{accent(s.id), s.shownText(), label(orDash(s.tag)), highlight(orDash(s.alias))},
+	})
+
+	if s.secret && clearAfter() > 0 {
+		fmt.Printf("🔑 Clipboard clears in %s\n", clearAfter())
+	}
+	fmt.Println("💡 Tip: Paste it anywhere with Ctrl+V")
+	return nil
+}
+
+// ------------------ GET / RUN ------------------
+
+// useSnippet looks up a snippet and counts the lookup as a use.
+func useSnippet(idOrAlias, kind string) (snippet, error) {
+	var s snippet
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(vault))
+		var ok bool
+		if s, ok = findSnippet(tx, vault, idOrAlias); !ok {
+			return notFound(idOrAlias)
+		}
+		s.useCount++
+		s.created = time.Now().Unix()
+		if err := logEvent(tx, kind, vault, s.id); err != nil {
+			return err
+		}
+		return putSnippet(b, s.id, s)
+	})
+	if errors.Is(err, ErrNotFound) {
+		if ps, ok := findProjectSnippet(idOrAlias); ok {
+			return ps, nil
+		}
+	}
+	return s, err
+}
+
+// getSnippet writes the snippet's text, and nothing else, to stdout.
+// Errors go to stderr via main so stdout stays safe to pipe.
+func getSnippet(idOrAlias string, pairs []string, render bool) error {
+	vars, err := parseVars(pairs)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	s, err := useSnippet(idOrAlias, eventGet)
+	if err != nil {
+		return err
+	}
+
+	text := s.text
+	if render || len(vars) > 0 {
+		if text, err = renderTemplate(text, vars); err != nil {
+			return usageError{err.Error()}
+		}
+	}
+	_, err = os.Stdout.WriteString(text)
+	return err
+}
+
+// runSnippet executes a snippet through the shell after filling its
+// placeholders from args and asking for confirmation. The command's own
+// exit code is passed through as an exitStatus.
+func runSnippet(idOrAlias string, args, pairs []string, yes bool) error {
+	vars, err := parseVars(pairs)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+	s, err := useSnippet(idOrAlias, eventRun)
+	if err != nil {
+		return err
+	}
+
+	command, err := renderCommand(s.text, args, vars)
+	if err != nil {
+		return usageError{err.Error()}
+	}
+
+	if !yes {
+		fmt.Fprintf(os.Stderr, "%s %s\n", theme.accent.Sprint("▶ Run:"), command)
+		fmt.Fprint(os.Stderr, theme.highlight.Sprint("Proceed? [y/N] "))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("aborted")
+		}
+	}
+
+	// The DB is not needed while the command runs; don't hold the lock.
+	releaseDB()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		shell := os.Getenv("SHELL")
+		if shell == "" {
+			shell = "sh"
+		}
+		cmd = exec.Command(shell, "-c", command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return exitStatus(exit.ExitCode())
+		}
+		return err
+	}
+	return nil
+}
+
+// shellQuote quotes an extra argument for the platform's shell.
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\$`|&;<>()*?[]{}!#~") {
+		return arg
+	}
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// ------------------ PIN TOGGLE ------------------
+
+func pinSnippet(idOrAlias string) error {
+	var s snippet
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(vault))
+
+		var ok bool
+		if s, ok = findSnippet(tx, vault, idOrAlias); !ok {
+			return notFound(idOrAlias)
+		}
+
+		// Toggle pin state
+		s.pinned = !s.pinned
+
+		// Save updated snippet
+		s.created = time.Now().Unix()
+		return putSnippet(b, s.id, s)
+	})
+	if err != nil {
+		return err
+	}
+
+	action := "📌 Snippet pinned"
+	if !s.pinned {
+		action = "📍 Snippet unpinned"
+	}
+
+	// Polished output
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+
+	fmt.Printf("%s [%s]\n", action, accent(s.id))
+
+	printSnippetTable([][]string{
+		{accent(s.id), s.shownText(), label(orDash(s.tag)), highlight(orDash(s.alias))},
+	})
+
+	if s.pinned {
+		fmt.Println("💡 Tip: Run 'grb list' to see pinned snippets at the top")
+	} else {
+		fmt.Println("💡 Tip: Run 'grb list' to see all snippets")
+	}
+	return nil
+}
+
+// ------------------ UPDATE ALIAS ------------------
+func updateAlias(idOrAlias, newAlias string) error {
+	var s snippet
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(vault))
+
+		var ok bool
+		if s, ok = findSnippet(tx, vault, idOrAlias); !ok {
+			return notFound(idOrAlias)
+		}
+		if err := claimAlias(tx, vault, newAlias, s.id); err != nil {
+			return err
+		}
+		if s.alias != newAlias {
+			if err := releaseAlias(tx, vault, s.alias, s.id); err != nil {
+				return err
+			}
+		}
+		s.alias = newAlias
+		return putSnippet(b, s.id, s)
+	})
+	if err != nil {
+		return err
+	}
+
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+	success := theme.success.SprintFunc()
+
+	fmt.Printf("%s Updated alias for snippet [%s]\n", success("✅"), accent(s.id))
+
+	printSnippetTable([][]string{
+		{accent(s.id), s.shownText(), label(s.tag), highlight(s.alias)},
+	})
+
+	fmt.Println("💡 Tip: Run 'grb list' to confirm changes")
+	return nil
+}
+
+// listAliases prints every alias with the snippet it points to.
+func listAliases() error {
+	rows := [][]string{}
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(vault))
+		return tx.Bucket(aliasesKey(vault)).ForEach(func(alias, id []byte) error {
+			if v := b.Get(id); v != nil {
+				s := parseSnippet(id, v)
+				rows = append(rows, []string{accent(s.id), s.shownText(), label(orDash(s.tag)), highlight(string(alias))})
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		say(theme.highlight, "⚠ No aliases yet.")
+		fmt.Println("💡 Tip: Use 'grb alias <id> <name>' to add one")
+		return nil
+	}
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Printf("%s (total: %d)\n", accent("📖 Aliases"), len(rows))
+	fmt.Println("─────────────────────────────────────────────")
+	printSnippetTable(rows)
+	return nil
+}
+
+// ------------------ DELETE ------------------
+
+func deleteSnippet(idOrAlias string) error {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		s, ok := findSnippet(tx, vault, idOrAlias)
+		if !ok {
+			return notFound(idOrAlias)
+		}
+		return dropSnippet(tx, vault, s)
+	})
+	if err != nil {
+		return err
+	}
+	say(theme.danger, "🗑 Snippet deleted!")
+	return nil
+}
+
+// ------------------ CLEAR ------------------
+
+func clearSnippets(all bool, tag string, unpinned bool) error {
+	var matched []snippet
+
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(vault))
+		c := b.Cursor()
+
+		// Collect first: deleting while the cursor walks skips keys.
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			s := parseSnippet(k, v)
+			if all || (tag != "" && hasTag(s.tag, tag)) || (unpinned && !s.pinned) {
+				matched = append(matched, s)
+			}
+		}
+		for _, s := range matched {
+			if err := dropSnippet(tx, vault, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, s := range matched {
+		say(theme.danger, "🗑 Deleted [%s] %s (%s) %s", s.id, s.shownText(), s.tag, s.alias)
+	}
+	if len(matched) == 0 {
+		say(theme.highlight, "⚠ No matching snippets found.")
+	} else {
+		say(theme.success, "✅ %d snippet(s) deleted.", len(matched))
+	}
+	return nil
+}
+
+// ------------------ EDIT ------------------
+
+func editSnippet(idOrAlias string) error {
+	var original snippet
+
+	// Find snippet
+	err := db.View(func(tx *bbolt.Tx) error {
+		var ok bool
+		if original, ok = findSnippet(tx, vault, idOrAlias); !ok {
+			return notFound(idOrAlias)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	id := original.id
+	tmpFile, err := editorTempFile("grb-edit-*.txt", original.text)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile)
+
+	// Open in default editor
+	if err := runEditor(tmpFile); err != nil {
+		return fmt.Errorf("editor: %w", err)
+	}
+
+	// Read back and update DB
+	edited, err := os.ReadFile(tmpFile)
+	if err != nil {
+		return err
+	}
+	newText := string(edited)
+
+	updated := original
+	updated.text = newText
+	err = db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(vault))
+		return putSnippet(b, id, updated)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Polished output
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+	success := theme.success.SprintFunc()
+
+	fmt.Printf("%s Snippet [%s] updated\n", success("✅"), accent(id))
+
+	fmt.Println("Before")
+	fmt.Println("─────────────────────────────────────────────")
+	printSnippetTable([][]string{
+		{accent(id), original.shownText(), label(original.tag), highlight(original.alias)},
+	})
+
+	fmt.Println("\nAfter")
+	fmt.Println("─────────────────────────────────────────────")
+	printSnippetTable([][]string{
+		{accent(id), updated.shownText(), label(original.tag), highlight(original.alias)},
+	})
+
+	fmt.Println("💡 Tip: Run 'grb list' to confirm all snippets")
+	return nil
+}
+
+// editorTempFile writes text to a new temporary file for the editor.
+// os.CreateTemp picks an unpredictable name and creates it with mode 0600,
+// so secrets and decrypted text aren't readable by other users and a
+// planted symlink can't redirect the write. The caller removes the file.
+func editorTempFile(pattern, text string) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(text)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// runEditor opens path in the configured editor and waits for it to exit.
+// The editor may include arguments, e.g. "code --wait".
+func runEditor(path string) error {
+	cmd := editorCommand(path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// editorCommand is the configured editor command for path.
+func editorCommand(path string) *exec.Cmd {
+	editor := strings.Fields(active.Editor)
+	if len(editor) == 0 {
+		editor = strings.Fields(defaultSettings().Editor)
+	}
+	return exec.Command(editor[0], append(editor[1:], path)...)
+}
+
+// ------------------ STATS ------------------
+
+func showStats() error {
+	total := 0
+	tagCount := map[string]int{}
+	var topSnippet string
+	maxCount := 0
+	var topTag string
+	maxTagCount := 0
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(vault))
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			s := parseSnippet(k, v)
+			total++
+
+			for _, tag := range splitTags(s.tag) {
+				tagCount[tag]++
+				if tagCount[tag] > maxTagCount {
+					maxTagCount = tagCount[tag]
+					topTag = tag
+				}
+			}
+			if s.useCount > maxCount {
+				maxCount = s.useCount
+				topSnippet = fmt.Sprintf("[%s] %s", s.id, s.shownText())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	accent := theme.accent.SprintFunc()
+	success := theme.success.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	danger := theme.danger.SprintFunc()
+
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Println(accent("📊 grb Stats"))
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Printf("%-18s : %s\n", "Total snippets", success(fmt.Sprintf("%d", total)))
+	if topSnippet != "" {
+		fmt.Printf("%-18s : %s (%s)\n", "Most used", highlight(topSnippet), danger(fmt.Sprintf("🔥 %d times", maxCount)))
+	}
+	if topTag != "" {
+		fmt.Printf("%-18s : 🏷 %s (%d snippets)\n", "Top tag", highlight(topTag), maxTagCount)
+	}
+
+	if len(tagCount) > 0 {
+		fmt.Println("─────────────────────────────────────────────")
+		fmt.Println(accent("Tag Breakdown"))
+		fmt.Println("─────────────────────────────────────────────")
+
+		// Simple table for tag breakdown
+		fmt.Println("┌──────────────────────┬───────┐")
+		fmt.Printf("│ %-20s │ %-5s │\n", "Tag", "Count")
+		fmt.Println("├──────────────────────┼───────┤")
+
+		tags := make([]string, 0, len(tagCount))
+		for t := range tagCount {
+			tags = append(tags, t)
+		}
+		sort.Slice(tags, func(i, j int) bool {
+			if tagCount[tags[i]] != tagCount[tags[j]] {
+				return tagCount[tags[i]] > tagCount[tags[j]]
+			}
+			return tags[i] < tags[j]
+		})
+		for _, t := range tags {
+			c := tagCount[t]
+			tagDisplay := highlight("🏷 " + t)
+			countDisplay := success(fmt.Sprintf("%d", c))
+			fmt.Printf("│ %s │ %s │\n",
+				padRight(tagDisplay, 20),
+				padRight(countDisplay, 5))
+		}
+		fmt.Println("└──────────────────────┴───────┘")
+	}
+	return nil
+}
+
+// ------------------ DAEMON ------------------
+
+// startDaemon runs until interrupted; it only returns on a setup error.
+func startDaemon() error {
+	say(theme.highlight, "📡 grb Daemon started. Watching clipboard...")
+	fmt.Println("─────────────────────────────────────────────")
+
+	// Only hold the DB while writing a capture so the TUI and other
+	// commands can use it in between.
+	releaseDB()
+
+	n, err := startNotifier()
+	if err != nil {
+		say(theme.highlight, "⚠ Live updates disabled: %v", err)
+	} else {
+		defer n.close()
+	}
+
+	last := ""
+	var nextSnapshot time.Time
+	if encryption != nil {
+		say(theme.highlight, "🔒 The database is encrypted: captures are skipped until 'grb unlock'")
+	}
+
+	for {
+		if time.Now().After(nextSnapshot) {
+			nextSnapshot = time.Now().Add(time.Minute)
+			if err := acquireDB(); err == nil {
+				file, err := takeSnapshot()
+				releaseDB()
+				if err != nil {
+					say(theme.danger, "❌ Snapshot failed: %v", err)
+				} else if file != "" {
+					say(theme.success, "💾 Snapshot saved to %s", file)
+				}
+			}
+		}
+
+		text, _ := clipboard.ReadAll()
+		if text != "" && text != last {
+			var newID string
+
+			if err := acquireDB(); err != nil {
+				say(theme.danger, "❌ %v", err)
+				time.Sleep(pollInterval())
+				continue
+			}
+			secret := false
+			err := db.Update(func(tx *bbolt.Tx) error {
+				// 'grb encrypt' may have run since the daemon started.
+				loadEncryption(tx)
+				if encryption != nil && sessionKey() == nil {
+					return ErrSealed
+				}
+				// A copied secret must not come back as a plain snippet.
+				if secret = isSecretText(tx, text); secret {
+					return nil
+				}
+				b := tx.Bucket(snippetsKey(vault))
+				id, _ := b.NextSequence()
+				newID = fmt.Sprintf("%d", id)
+
+				s := snippet{text: text, tag: "auto", created: time.Now().Unix()}
+				if err := logEvent(tx, eventCapture, vault, newID); err != nil {
+					return err
+				}
+				return putSnippet(b, newID, s)
+			})
+			releaseDB()
+			if errors.Is(err, ErrSealed) {
+				say(theme.highlight, "🔒 Locked: capture skipped")
+				last = text
+				time.Sleep(pollInterval())
+				continue
+			}
+			if err != nil {
+				say(theme.danger, "❌ %v", err)
+				time.Sleep(pollInterval())
+				continue
+			}
+			if secret {
+				say(theme.highlight, "🔑 Secret on the clipboard: capture skipped")
+				last = text
+				time.Sleep(pollInterval())
+				continue
+			}
+			n.broadcast("changed " + newID)
+
+			// Colors
+			accent := theme.accent.SprintFunc()
+			highlight := theme.highlight.SprintFunc()
+			label := theme.label.SprintFunc()
+			success := theme.success.SprintFunc()
+
+			// Polished output
+			fmt.Printf("\n%s snippet [%s]\n", success("✅ Captured"), accent(newID))
+
+			printSnippetTable([][]string{
+				{accent(newID), text, label("auto"), highlight("-")},
+			})
+
+			fmt.Println("💡 Tip: Press Ctrl+C to stop daemon")
+
+			last = text
+		}
+		time.Sleep(pollInterval())
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ------------------ PROJECT SNIPPETS ------------------
+
+// Project snippets live in the repo they belong to: a .grb.yaml file, or
+// any *.yaml file in a .grb/ directory, found by walking up from the
+// working directory. They are read-only for every command except
+// 'grb save --project', and get ids p1, p2, ... in file order.
+//
+//	snippets:
+//	  - text: go test ./...
+//	    tag: build
+//	    alias: t
+
+type projectSnippet struct {
+	Text  string `yaml:"text"`
+	Tag   string `yaml:"tag,omitempty"`
+	Alias string `yaml:"alias,omitempty"`
+}
+
+type projectFile struct {
+	Snippets []projectSnippet `yaml:"snippets"`
+}
+
+// findProject walks up from the working directory and returns the
+// directory holding .grb.yaml or .grb/ and the snippet files in it.
+func findProject() (string, []string) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", nil
+	}
+	for {
+		if file := filepath.Join(dir, ".grb.yaml"); isFile(file) {
+			return dir, []string{file}
+		}
+		if info, err := os.Stat(filepath.Join(dir, ".grb")); err == nil && info.IsDir() {
+			var files []string
+			for _, pattern := range []string{"*.yaml", "*.yml"} {
+				matches, _ := filepath.Glob(filepath.Join(dir, ".grb", pattern))
+				files = append(files, matches...)
+			}
+			sort.Strings(files)
+			return dir, files
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// loadProjectSnippets reads the project's snippets. Without a project it
+// returns nothing; a file that doesn't parse is an error.
+func loadProjectSnippets() ([]snippet, error) {
+	_, files := findProject()
+	var snippets []snippet
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var pf projectFile
+		if err := yaml.Unmarshal(data, &pf); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, ps := range pf.Snippets {
+			snippets = append(snippets, snippet{
+				id:      fmt.Sprintf("p%d", len(snippets)+1),
+				text:    ps.Text,
+				tag:     ps.Tag,
+				alias:   ps.Alias,
+				project: true,
+			})
+		}
+	}
+	return snippets, nil
+}
+
+// projectSnippets is loadProjectSnippets for listings, which show what
+// they can and skip a broken project file.
+func projectSnippets() []snippet {
+	snippets, _ := loadProjectSnippets()
+	return snippets
+}
+
+// findProjectSnippet looks a project snippet up by id (p1, ...) or alias.
+func findProjectSnippet(idOrAlias string) (snippet, bool) {
+	for _, s := range projectSnippets() {
+		if s.id == idOrAlias || s.alias == idOrAlias {
+			return s, true
+		}
+	}
+	return snippet{}, false
+}
+
+// projectRoot is where 'grb save --project' creates .grb.yaml when the
+// project has no snippet file yet: the enclosing git repo, or the working
+// directory.
+func projectRoot() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, nil
+		}
+		if filepath.Dir(dir) == dir {
+			return cwd, nil
+		}
+	}
+}
+
+// saveProjectSnippet appends a snippet to the project's first snippet
+// file, creating .grb.yaml if needed. Comments and layout of the existing
+// file are kept. It returns the file written and the new snippet's id.
+func saveProjectSnippet(text, tag, alias string) (string, string, error) {
+	if err := validateAlias(alias); err != nil {
+		return "", "", err
+	}
+	existing, err := loadProjectSnippets()
+	if err != nil {
+		return "", "", err
+	}
+	for _, s := range existing {
+		if alias != "" && s.alias == alias {
+			return "", "", fmt.Errorf("%w: %q belongs to project snippet [%s]", ErrAliasTaken, alias, s.id)
+		}
+	}
+
+	_, files := findProject()
+	var file string
+	var doc yaml.Node
+	var before projectFile // the snippets already in file
+	if len(files) > 0 {
+		file = files[0]
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", "", err
+		}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return "", "", fmt.Errorf("%s: %w", file, err)
+		}
+		yaml.Unmarshal(data, &before)
+		if len(doc.Content) == 0 {
+			// yaml drops the comments of a file with nothing else in it.
+			doc.HeadComment = strings.TrimSpace(string(data))
+		}
+	} else {
+		root, err := projectRoot()
+		if err != nil {
+			return "", "", err
+		}
+		file = filepath.Join(root, ".grb.yaml")
+	}
+
+	var item yaml.Node
+	if err := item.Encode(projectSnippet{Text: text, Tag: tag, Alias: alias}); err != nil {
+		return "", "", err
+	}
+	if err := appendSnippetNode(&doc, &item); err != nil {
+		return "", "", fmt.Errorf("%s: %w", file, err)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		return "", "", err
+	}
+	// file is the first snippet file, so ids run through it first.
+	return file, fmt.Sprintf("p%d", len(before.Snippets)+1), nil
+}
+
+// appendSnippetNode adds item to the "snippets" list of doc, creating the
+// document or the list when they don't exist. An empty or comment-only
+// file counts as an empty mapping.
+func appendSnippetNode(doc, item *yaml.Node) error {
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	root := doc.Content[0]
+	if root.Kind == yaml.ScalarNode && root.Tag == "!!null" {
+		root.Kind, root.Tag, root.Value = yaml.MappingNode, "!!map", ""
+	}
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("expected a mapping with a snippets list")
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "snippets" {
+			list := root.Content[i+1]
+			if list.Kind == yaml.ScalarNode && list.Tag == "!!null" {
+				list.Kind, list.Tag, list.Value = yaml.SequenceNode, "!!seq", ""
+			}
+			if list.Kind != yaml.SequenceNode {
+				return fmt.Errorf("snippets must be a list")
+			}
+			list.Content = append(list.Content, item)
+			return nil
+		}
+	}
+	root.Content = append(root.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "snippets"},
+		&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{item}},
+	)
+	return nil
+}
+
+// projectRows turns project snippets into table rows.
+func projectRows(snippets []snippet) [][]string {
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+
+	var rows [][]string
+	for _, s := range snippets {
+		rows = append(rows, []string{accent(s.id), s.text, label(orDash(s.tag)), highlight(orDash(s.alias))})
+	}
+	return rows
+}
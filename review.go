@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"go.etcd.io/bbolt"
+)
+
+// ------------------ REVIEW ------------------
+
+// 'grb review' walks the snippets nobody has used for review_after (and
+// that were used at most --max-uses times), oldest first, and asks what to
+// do with each: keep it (counts as a use, so it won't come up again for a
+// while), archive it, delete it, retag it or edit it. Archived snippets
+// stay in the DB and keep working by id or alias, but list, search and the
+// TUI leave them out unless --archived is given.
+
+// showArchived makes list, search and the TUI show archived snippets
+// instead of the others (--archived).
+var showArchived bool
+
+// reviewCandidates returns the unpinned snippets that unusedSince finds
+// for unused ago and that were used at most maxUses times, oldest first.
+func reviewCandidates(unused time.Duration, maxUses int) ([]unusedSnippet, error) {
+	var queue []unusedSnippet
+	err := db.View(func(tx *bbolt.Tx) error {
+		for _, s := range unusedSince(tx, vault, time.Now().Add(-unused)) {
+			if !s.pinned && s.useCount <= maxUses {
+				queue = append(queue, s)
+			}
+		}
+		return nil
+	})
+	return queue, err
+}
+
+// changeSnippet re-reads the snippet with id, applies change and writes it
+// back.
+func changeSnippet(id string, change func(*snippet)) (snippet, error) {
+	var s snippet
+	err := db.Update(func(tx *bbolt.Tx) error {
+		var ok bool
+		if s, ok = findSnippet(tx, vault, id); !ok {
+			return notFound(id)
+		}
+		change(&s)
+		return putSnippet(tx.Bucket(snippetsKey(vault)), s.id, s)
+	})
+	return s, err
+}
+
+type reviewModel struct {
+	queue  []unusedSnippet
+	pos    int
+	unused time.Duration
+	prompt string // "", "tag" or "delete"
+	input  textinput.Model
+	status string
+	tmp    string // file being edited
+	width  int
+	tally  map[string]int
+}
+
+// editedMsg is sent when the editor started by 'e' exits.
+type editedMsg struct{ err error }
+
+func (m reviewModel) Init() tea.Cmd { return nil }
+
+func (m reviewModel) current() snippet { return m.queue[m.pos].snippet }
+
+// next moves to the following snippet, quitting after the last one.
+func (m reviewModel) next(action, status string) (tea.Model, tea.Cmd) {
+	m.tally[action]++
+	m.status = status
+	m.pos++
+	if m.pos == len(m.queue) {
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m reviewModel) fail(err error) (tea.Model, tea.Cmd) {
+	m.status = theme.danger.Sprintf("❌ %v", err)
+	return m, nil
+}
+
+func (m reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg.(type) {
+	case tea.KeyMsg, editedMsg:
+		// As in the TUI, the DB is only held while handling a message.
+		if err := acquireDB(); err != nil {
+			return m.fail(err)
+		}
+		defer releaseDB()
+	}
+	return m.update(msg)
+}
+
+func (m reviewModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		return m, nil
+	case editedMsg:
+		return m.finishEdit(msg.err)
+	case tea.KeyMsg:
+		if m.prompt != "" {
+			return m.updatePrompt(msg)
+		}
+		s := m.current()
+		switch msg.String() {
+		case "k", "enter":
+			err := db.Update(func(tx *bbolt.Tx) error {
+				return logEvent(tx, eventKeep, vault, s.id)
+			})
+			if err != nil {
+				return m.fail(err)
+			}
+			return m.next("kept", theme.success.Sprintf("👍 Kept [%s]", s.id))
+		case "a":
+			_, err := changeSnippet(s.id, func(s *snippet) { s.archived = true })
+			if err != nil {
+				return m.fail(err)
+			}
+			return m.next("archived", theme.label.Sprintf("📦 Archived [%s]", s.id))
+		case "d":
+			m.prompt = "delete"
+			return m, nil
+		case "t":
+			m.prompt = "tag"
+			m.input.SetValue(s.tag)
+			m.input.CursorEnd()
+			return m, m.input.Focus()
+		case "e":
+			return m.startEdit()
+		case "s", "right":
+			return m.next("skipped", fmt.Sprintf("⏭ Skipped [%s]", s.id))
+		case "q", "esc", "ctrl+c":
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+func (m reviewModel) updatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := m.current()
+	if m.prompt == "delete" {
+		m.prompt = ""
+		if k := msg.String(); k != "y" && k != "Y" {
+			return m, nil
+		}
+		err := db.Update(func(tx *bbolt.Tx) error {
+			cur, ok := findSnippet(tx, vault, s.id)
+			if !ok {
+				return notFound(s.id)
+			}
+			return dropSnippet(tx, vault, cur)
+		})
+		if err != nil {
+			return m.fail(err)
+		}
+		return m.next("deleted", theme.danger.Sprintf("🗑 Deleted [%s]", s.id))
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.prompt = ""
+		m.input.Blur()
+		return m, nil
+	case "enter":
+		m.prompt = ""
+		m.input.Blur()
+		tag := strings.TrimSpace(m.input.Value())
+		updated, err := changeSnippet(s.id, func(s *snippet) { s.tag = tag })
+		if err != nil {
+			return m.fail(err)
+		}
+		m.queue[m.pos].snippet = updated
+		m.tally["retagged"]++
+		m.status = theme.success.Sprintf("🏷 Retagged [%s] %s", s.id, orDash(tag))
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// startEdit suspends the TUI and opens the snippet in the editor.
+func (m reviewModel) startEdit() (tea.Model, tea.Cmd) {
+	tmp, err := editorTempFile("grb-review-*.txt", m.current().text)
+	if err != nil {
+		return m.fail(err)
+	}
+	m.tmp = tmp
+	return m, tea.ExecProcess(editorCommand(m.tmp), func(err error) tea.Msg { return editedMsg{err} })
+}
+
+// finishEdit saves the edited text. The snippet stays on screen so it can
+// be kept or archived next.
+func (m reviewModel) finishEdit(editErr error) (tea.Model, tea.Cmd) {
+	defer os.Remove(m.tmp)
+	if editErr != nil {
+		return m.fail(fmt.Errorf("editor: %w", editErr))
+	}
+	edited, err := os.ReadFile(m.tmp)
+	if err != nil {
+		return m.fail(err)
+	}
+	s := m.current()
+	if string(edited) == s.text {
+		m.status = "✏ No changes"
+		return m, nil
+	}
+	updated, err := changeSnippet(s.id, func(s *snippet) { s.text = string(edited) })
+	if err != nil {
+		return m.fail(err)
+	}
+	m.queue[m.pos].snippet = updated
+	m.tally["edited"]++
+	m.status = theme.success.Sprintf("✏ Edited [%s]", s.id)
+	return m, nil
+}
+
+func (m reviewModel) View() string {
+	if m.pos >= len(m.queue) {
+		return ""
+	}
+	s := m.current()
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %d/%d  %s\n\n", accent("🧹 Review"), m.pos+1, len(m.queue),
+		label(fmt.Sprintf("(unused for %s)", formatPeriod(m.unused))))
+	fmt.Fprintf(&b, "[%s]  🏷 %s  %s\n", accent(s.id), label(orDash(s.tag)), highlight(orDash(s.alias)))
+	days := int(time.Since(m.queue[m.pos].lastUsed).Hours() / 24)
+	fmt.Fprintf(&b, "Last used %d days ago · used %d time(s)\n", days, s.useCount)
+
+	width := 76
+	if m.width > 0 && m.width-4 < width {
+		width = m.width - 4
+	}
+	text := s.shownText()
+	if lines := strings.Split(text, "\n"); len(lines) > 12 {
+		text = strings.Join(lines[:12], "\n") + "\n" + label(fmt.Sprintf("… %d more lines", len(lines)-12))
+	}
+	box := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1).Width(width)
+	b.WriteString(box.Render(text) + "\n")
+
+	switch m.prompt {
+	case "delete":
+		b.WriteString(theme.danger.Sprintf("🗑 Delete [%s]? (y/n)", s.id))
+	case "tag":
+		b.WriteString(m.input.View() + "  " +
+			theme.accent.Sprint("Enter confirm") + " | " + theme.highlight.Sprint("Esc cancel"))
+	default:
+		b.WriteString(theme.success.Sprint("k keep") + " | " + theme.label.Sprint("a archive") + " | " +
+			theme.danger.Sprint("d delete") + " | " + accent("t retag") + " | " + accent("e edit") + " | " +
+			"s skip | " + highlight("q quit"))
+	}
+	if m.status != "" {
+		b.WriteString("\n" + m.status)
+	}
+	return b.String()
+}
+
+// reviewSnippets runs the review TUI and prints what was done.
+func reviewSnippets(unused time.Duration, maxUses int) error {
+	queue, err := reviewCandidates(unused, maxUses)
+	if err != nil {
+		return err
+	}
+	if len(queue) == 0 {
+		say(theme.success, "🧹 Nothing to review: no snippet has gone unused for %s", formatPeriod(unused))
+		fmt.Printf("💡 Tip: Pinned and archived snippets are skipped, and so are ones used more than %d time(s)\n", maxUses)
+		return nil
+	}
+
+	input := textinput.New()
+	input.Placeholder = "tags, comma separated"
+	m := reviewModel{queue: queue, unused: unused, input: input, tally: map[string]int{}}
+	releaseDB()
+	final, err := tea.NewProgram(m).Run()
+	if err != nil {
+		return err
+	}
+
+	tally := final.(reviewModel).tally
+	var parts []string
+	for _, action := range []string{"kept", "archived", "deleted", "retagged", "edited", "skipped"} {
+		if n := tally[action]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, action))
+		}
+	}
+	if len(parts) == 0 {
+		parts = []string{"no changes"}
+	}
+	say(theme.success, "🧹 Review done: %s", strings.Join(parts, ", "))
+	if tally["archived"] > 0 {
+		fmt.Println("💡 Tip: 'grb list --archived' shows archived snippets; 'grb archive <id>' restores one")
+	}
+	return nil
+}
+
+// ------------------ ARCHIVE ------------------
+
+// toggleArchive archives or restores a snippet.
+func toggleArchive(idOrAlias string) error {
+	var s snippet
+	err := db.Update(func(tx *bbolt.Tx) error {
+		var ok bool
+		if s, ok = findSnippet(tx, vault, idOrAlias); !ok {
+			return notFound(idOrAlias)
+		}
+		s.archived = !s.archived
+		return putSnippet(tx.Bucket(snippetsKey(vault)), s.id, s)
+	})
+	if err != nil {
+		return err
+	}
+
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+
+	action := "📦 Snippet archived"
+	if !s.archived {
+		action = "📤 Snippet restored from the archive"
+	}
+	fmt.Printf("%s [%s]\n", action, accent(s.id))
+	printSnippetTable([][]string{
+		{accent(s.id), s.shownText(), label(orDash(s.tag)), highlight(orDash(s.alias))},
+	})
+	if s.archived {
+		fmt.Println("💡 Tip: It still works by id or alias; 'grb list --archived' shows it")
+	}
+	return nil
+}
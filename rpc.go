@@ -0,0 +1,557 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// ------------------ RPC ------------------
+
+// 'grb rpc --stdio' serves the library to scripts and assistants as
+// JSON-RPC 2.0, one message per line on stdin/stdout (batches included).
+// Methods take named params; their JSON schemas are listed by
+// rpc.discover. The same methods are MCP tools (snippets_search, ...), so
+// the command can be registered as an MCP server as is.
+//
+// Params default to the current vault and take "vault" to pick another.
+// Lookups fall back to the project's .grb.yaml like the CLI does. Secret
+// snippets come back masked and can't be rendered; clipboard.copy still
+// copies them, so their text never passes through the caller.
+
+type rpcMethod struct {
+	name        string
+	description string
+	schema      string // JSON schema of the params object
+	call        func(json.RawMessage) (interface{}, error)
+}
+
+var rpcMethods = []rpcMethod{
+	{
+		name:        "snippets.search",
+		description: "Search snippet text, tags and aliases. Pinned and most used snippets come first.",
+		schema: `{
+  "type": "object",
+  "properties": {
+    "query": {"type": "string", "description": "Case-insensitive text to look for"},
+    "tag": {"type": "string", "description": "Only snippets with this tag"},
+    "archived": {"type": "boolean", "description": "Search archived snippets instead of the others", "default": false},
+    "limit": {"type": "integer", "minimum": 1, "default": 20},
+    "vault": {"type": "string", "description": "Vault to use; defaults to the current vault"}
+  },
+  "required": ["query"],
+  "additionalProperties": false
+}`,
+		call: rpcSearch,
+	},
+	{
+		name:        "snippets.get",
+		description: "Get one snippet by id or alias, with the names of its {{placeholders}}.",
+		schema: `{
+  "type": "object",
+  "properties": {
+    "ref": {"type": "string", "description": "Snippet id or alias"},
+    "vault": {"type": "string", "description": "Vault to use; defaults to the current vault"}
+  },
+  "required": ["ref"],
+  "additionalProperties": false
+}`,
+		call: rpcGet,
+	},
+	{
+		name:        "snippets.save",
+		description: "Save a new snippet.",
+		schema: `{
+  "type": "object",
+  "properties": {
+    "text": {"type": "string", "minLength": 1},
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "alias": {"type": "string", "description": "Unique name; no whitespace, '|' or leading '-', not all digits"},
+    "pinned": {"type": "boolean", "default": false},
+    "secret": {"type": "boolean", "default": false},
+    "vault": {"type": "string", "description": "Vault to use; defaults to the current vault"}
+  },
+  "required": ["text"],
+  "additionalProperties": false
+}`,
+		call: rpcSave,
+	},
+	{
+		name:        "snippets.render",
+		description: "Fill in a snippet's {{placeholders}} and return the text. Placeholders without a value use their default. Counts as a use.",
+		schema: `{
+  "type": "object",
+  "properties": {
+    "ref": {"type": "string", "description": "Snippet id or alias"},
+    "vars": {"type": "object", "additionalProperties": {"type": "string"}, "description": "Placeholder values by name"},
+    "vault": {"type": "string", "description": "Vault to use; defaults to the current vault"}
+  },
+  "required": ["ref"],
+  "additionalProperties": false
+}`,
+		call: rpcRender,
+	},
+	{
+		name:        "clipboard.copy",
+		description: "Copy a snippet, or any text, to the clipboard. Copying a snippet counts as a use.",
+		schema: `{
+  "type": "object",
+  "properties": {
+    "ref": {"type": "string", "description": "Snippet id or alias"},
+    "text": {"type": "string", "description": "Text to copy instead of a snippet"},
+    "vault": {"type": "string", "description": "Vault to use; defaults to the current vault"}
+  },
+  "oneOf": [{"required": ["ref"]}, {"required": ["text"]}],
+  "additionalProperties": false
+}`,
+		call: rpcCopy,
+	},
+}
+
+func findRPCMethod(name string) (rpcMethod, bool) {
+	for _, m := range rpcMethods {
+		if m.name == name {
+			return m, true
+		}
+	}
+	return rpcMethod{}, false
+}
+
+// toolName is a method's MCP tool name: snippets.search -> snippets_search.
+func toolName(method string) string {
+	return strings.ReplaceAll(method, ".", "_")
+}
+
+// strictParams decodes params into v, rejecting fields the schema doesn't
+// have.
+func strictParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		params = json.RawMessage("{}")
+	}
+	dec := json.NewDecoder(bytes.NewReader(params))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+// rpcVault is the vault named by params, or the current one.
+func rpcVault(tx *bbolt.Tx, name string) (string, error) {
+	if name == "" {
+		name = vault
+	}
+	if !vaultExists(tx, name) {
+		return "", fmt.Errorf("vault %w: %s", ErrNotFound, name)
+	}
+	return name, nil
+}
+
+// rpcSnippet is how rpc sends a snippet: toAPI, plus project snippets.
+func rpcSnippet(vaultName string, s snippet) apiSnippet {
+	out := toAPI(vaultName, s)
+	if s.project {
+		out.Vault, out.Project = "", true
+	}
+	return out
+}
+
+// rpcFind looks ref up in the vault, then in the project, and hands the
+// vault's snippets to use, which may change them. use is skipped for
+// project snippets, which are read-only.
+func rpcFind(vaultName, ref string, use func(tx *bbolt.Tx, name string, s *snippet) error) (string, snippet, error) {
+	if ref == "" {
+		return "", snippet{}, usagef("ref is required")
+	}
+	var s snippet
+	missing := false
+	err := withDB(func() error {
+		run := db.View
+		if use != nil {
+			run = db.Update
+		}
+		return run(func(tx *bbolt.Tx) error {
+			var err error
+			if vaultName, err = rpcVault(tx, vaultName); err != nil {
+				return err
+			}
+			var ok bool
+			if s, ok = findSnippet(tx, vaultName, ref); !ok {
+				missing = true
+				return notFound(ref)
+			}
+			if use == nil {
+				return nil
+			}
+			if err := use(tx, vaultName, &s); err != nil {
+				return err
+			}
+			return putSnippet(tx.Bucket(snippetsKey(vaultName)), s.id, s)
+		})
+	})
+	if missing {
+		if ps, ok := findProjectSnippet(ref); ok {
+			return "", ps, nil
+		}
+	}
+	return vaultName, s, err
+}
+
+// countUse returns a use func that counts the lookup as a kind event.
+func countUse(kind string) func(tx *bbolt.Tx, name string, s *snippet) error {
+	return func(tx *bbolt.Tx, name string, s *snippet) error {
+		s.useCount++
+		s.created = time.Now().Unix()
+		return logEvent(tx, kind, name, s.id)
+	}
+}
+
+func rpcSearch(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Query    string `json:"query"`
+		Tag      string `json:"tag"`
+		Archived bool   `json:"archived"`
+		Limit    int    `json:"limit"`
+		Vault    string `json:"vault"`
+	}
+	if err := strictParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Limit == 0 {
+		p.Limit = 20
+	}
+	if strings.TrimSpace(p.Query) == "" || p.Limit < 1 {
+		return nil, usagef("query is required and limit must be at least 1")
+	}
+	q := strings.ToLower(p.Query)
+	match := func(s snippet) bool {
+		if s.archived != p.Archived || (p.Tag != "" && !hasTag(s.tag, p.Tag)) {
+			return false
+		}
+		// Secret text isn't searched, so a match doesn't give it away.
+		return (!s.secret && strings.Contains(strings.ToLower(s.text), q)) ||
+			strings.Contains(strings.ToLower(s.tag), q) ||
+			strings.Contains(strings.ToLower(s.alias), q)
+	}
+
+	var found []snippet
+	err := withDB(func() error {
+		return db.View(func(tx *bbolt.Tx) error {
+			var err error
+			if p.Vault, err = rpcVault(tx, p.Vault); err != nil {
+				return err
+			}
+			return tx.Bucket(snippetsKey(p.Vault)).ForEach(func(k, v []byte) error {
+				if s := parseSnippet(k, v); match(s) {
+					found = append(found, s)
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range projectSnippets() {
+		if match(s) {
+			found = append(found, s)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.pinned != b.pinned {
+			return a.pinned
+		}
+		if a.useCount != b.useCount {
+			return a.useCount > b.useCount
+		}
+		return lessID(a.id, b.id)
+	})
+
+	total := len(found)
+	if len(found) > p.Limit {
+		found = found[:p.Limit]
+	}
+	list := make([]apiSnippet, len(found))
+	for i, s := range found {
+		list[i] = rpcSnippet(p.Vault, s)
+	}
+	return map[string]interface{}{"snippets": list, "total": total}, nil
+}
+
+func rpcGet(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Ref   string `json:"ref"`
+		Vault string `json:"vault"`
+	}
+	if err := strictParams(params, &p); err != nil {
+		return nil, err
+	}
+	name, s, err := rpcFind(p.Vault, p.Ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	if !s.secret {
+		names = append(names, placeholders(s.text)...)
+	}
+	return map[string]interface{}{"snippet": rpcSnippet(name, s), "placeholders": names}, nil
+}
+
+func rpcSave(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Text   string   `json:"text"`
+		Tags   []string `json:"tags"`
+		Alias  string   `json:"alias"`
+		Pinned bool     `json:"pinned"`
+		Secret bool     `json:"secret"`
+		Vault  string   `json:"vault"`
+	}
+	if err := strictParams(params, &p); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(p.Text) == "" {
+		return nil, usagef("text is required")
+	}
+	var s snippet
+	err := withDB(func() error {
+		return db.Update(func(tx *bbolt.Tx) error {
+			var err error
+			if p.Vault, err = rpcVault(tx, p.Vault); err != nil {
+				return err
+			}
+			b := tx.Bucket(snippetsKey(p.Vault))
+			seq, _ := b.NextSequence()
+			s = snippet{
+				id: fmt.Sprintf("%d", seq), uuid: newUUID(), created: time.Now().Unix(),
+				text: p.Text, tag: strings.Join(splitTags(strings.Join(p.Tags, ",")), ","),
+				alias: strings.TrimSpace(p.Alias), pinned: p.Pinned, secret: p.Secret,
+			}
+			if err := claimAlias(tx, p.Vault, s.alias, s.id); err != nil {
+				return err
+			}
+			return putSnippet(b, s.id, s)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"snippet": rpcSnippet(p.Vault, s)}, nil
+}
+
+func rpcRender(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Ref   string            `json:"ref"`
+		Vars  map[string]string `json:"vars"`
+		Vault string            `json:"vault"`
+	}
+	if err := strictParams(params, &p); err != nil {
+		return nil, err
+	}
+	var text string
+	name, s, err := rpcFind(p.Vault, p.Ref, func(tx *bbolt.Tx, name string, s *snippet) error {
+		if s.secret {
+			return usagef("snippet [%s] is secret; copy it with clipboard.copy instead", s.id)
+		}
+		var err error
+		if text, err = renderTemplate(s.text, p.Vars); err != nil {
+			return usageError{err.Error()}
+		}
+		return countUse(eventGet)(tx, name, s)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.project {
+		if text, err = renderTemplate(s.text, p.Vars); err != nil {
+			return nil, usageError{err.Error()}
+		}
+	}
+	return map[string]interface{}{"text": text, "snippet": rpcSnippet(name, s)}, nil
+}
+
+func rpcCopy(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Ref   string  `json:"ref"`
+		Text  *string `json:"text"`
+		Vault string  `json:"vault"`
+	}
+	if err := strictParams(params, &p); err != nil {
+		return nil, err
+	}
+	if (p.Ref == "") == (p.Text == nil) {
+		return nil, usagef("give exactly one of ref or text")
+	}
+	if p.Text != nil {
+		if err := copyText(*p.Text, false); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"copied": true}, nil
+	}
+
+	// The clipboard tools run outside any transaction, and the use is only
+	// counted once the copy worked. The copy has happened by then, so a
+	// failure to count it is not reported as a failed call.
+	name, s, err := rpcFind(p.Vault, p.Ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := copyText(s.text, s.secret); err != nil {
+		return nil, err
+	}
+	if !s.project {
+		if _, used, err := rpcFind(name, s.id, countUse(eventCopy)); err == nil {
+			s = used
+		}
+	}
+	return map[string]interface{}{"copied": true, "snippet": rpcSnippet(name, s)}, nil
+}
+
+// ------------------ MCP ------------------
+
+// mcpProtocol is the MCP revision grb implements; a client asking for
+// another one is answered with this and decides whether to go on.
+const mcpProtocol = "2025-06-18"
+
+func rpcDispatch(req rpcRequest) (interface{}, error) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: `expected "jsonrpc": "2.0" and a method`}
+	}
+	if m, ok := findRPCMethod(req.Method); ok {
+		return m.call(req.Params)
+	}
+
+	switch req.Method {
+	case "rpc.discover":
+		methods := make([]map[string]interface{}, len(rpcMethods))
+		for i, m := range rpcMethods {
+			methods[i] = map[string]interface{}{
+				"name": m.name, "description": m.description, "params": json.RawMessage(m.schema),
+			}
+		}
+		return map[string]interface{}{"methods": methods}, nil
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		decodeParams(req.Params, &p)
+		version := mcpProtocol
+		if p.ProtocolVersion != "" && p.ProtocolVersion < mcpProtocol {
+			version = p.ProtocolVersion
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "grb", "version": "1.0.0"},
+		}, nil
+	case "tools/list":
+		tools := make([]map[string]interface{}, len(rpcMethods))
+		for i, m := range rpcMethods {
+			tools[i] = map[string]interface{}{
+				"name": toolName(m.name), "description": m.description, "inputSchema": json.RawMessage(m.schema),
+			}
+		}
+		return map[string]interface{}{"tools": tools}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := decodeParams(req.Params, &p); err != nil {
+			return nil, err
+		}
+		for _, m := range rpcMethods {
+			if toolName(m.name) == p.Name {
+				return toolResult(m.call(p.Arguments))
+			}
+		}
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "unknown tool: " + p.Name}
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+// toolResult wraps a method's result as an MCP tool result. Failures are
+// reported in the result, as MCP asks, so the model can see them.
+func toolResult(result interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": err.Error()}},
+			"isError": true,
+		}, nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"content":           []map[string]string{{"type": "text", "text": string(data)}},
+		"structuredContent": result,
+	}, nil
+}
+
+// handleRPC answers one line: a request, a notification or a batch. It
+// returns nil when there is nothing to send back.
+func handleRPC(line []byte) interface{} {
+	if line[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(line, &batch); err != nil {
+			return rpcReply(nil, nil, &rpcError{Code: rpcParseError, Message: err.Error()})
+		}
+		if len(batch) == 0 {
+			return rpcReply(nil, nil, &rpcError{Code: rpcInvalidRequest, Message: "empty batch"})
+		}
+		var replies []interface{}
+		for _, msg := range batch {
+			if reply := handleRPC(msg); reply != nil {
+				replies = append(replies, reply)
+			}
+		}
+		if len(replies) == 0 {
+			return nil
+		}
+		return replies
+	}
+
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return rpcReply(nil, nil, &rpcError{Code: rpcParseError, Message: err.Error()})
+	}
+	result, err := rpcDispatch(req)
+	if len(req.ID) == 0 {
+		return nil // notification
+	}
+	return rpcReply(req.ID, result, err)
+}
+
+// runRPC serves JSON-RPC on in and out until in is closed.
+func runRPC(in io.Reader, out io.Writer) error {
+	releaseDB()
+	r := bufio.NewReader(in)
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	for {
+		line, err := r.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			if reply := handleRPC(trimmed); reply != nil {
+				if err := enc.Encode(reply); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type rpcResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// rpcLines runs the server over lines and returns the raw reply lines.
+func rpcLines(t *testing.T, lines ...string) []string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := runRPC(strings.NewReader(strings.Join(lines, "\n")), &out); err != nil {
+		t.Fatal(err)
+	}
+	var replies []string
+	sc := bufio.NewScanner(&out)
+	for sc.Scan() {
+		replies = append(replies, sc.Text())
+	}
+	return replies
+}
+
+// rpcCall sends one request and returns its reply.
+func rpcCall(t *testing.T, method, params string) rpcResponse {
+	t.Helper()
+	replies := rpcLines(t, `{"jsonrpc": "2.0", "id": 1, "method": "`+method+`", "params": `+params+`}`)
+	if len(replies) != 1 {
+		t.Fatalf("%s: got %d replies, want 1", method, len(replies))
+	}
+	var reply rpcResponse
+	if err := json.Unmarshal([]byte(replies[0]), &reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func TestRPCBatchesAndNotifications(t *testing.T) {
+	useDB(t, filepath.Join(t.TempDir(), "grb.db"))
+	replies := rpcLines(t,
+		// A notification is carried out but not answered.
+		`{"jsonrpc": "2.0", "method": "snippets.save", "params": {"text": "echo one", "alias": "one"}}`,
+		`[{"jsonrpc": "2.0", "id": 1, "method": "ping"}, {"jsonrpc": "2.0", "method": "ping"}, `+
+			`{"jsonrpc": "2.0", "id": "b", "method": "snippets.get", "params": {"ref": "one"}}, `+
+			`{"jsonrpc": "2.0", "id": 3, "method": "no.such"}]`,
+		`[{"jsonrpc": "2.0", "method": "ping"}]`,
+		`[]`,
+		`{not json`,
+	)
+	if len(replies) != 3 {
+		t.Fatalf("got %d replies, want the batch's, the empty batch's and the parse error's:\n%s",
+			len(replies), strings.Join(replies, "\n"))
+	}
+
+	var batch []rpcResponse
+	if err := json.Unmarshal([]byte(replies[0]), &batch); err != nil {
+		t.Fatal(err)
+	}
+	if len(batch) != 3 {
+		t.Fatalf("batch has %d replies, want 3: %s", len(batch), replies[0])
+	}
+	if string(batch[0].ID) != "1" || batch[0].Error != nil {
+		t.Errorf("ping reply = %s", replies[0])
+	}
+	var got struct {
+		Snippet apiSnippet `json:"snippet"`
+	}
+	if err := json.Unmarshal(batch[1].Result, &got); err != nil || string(batch[1].ID) != `"b"` || got.Snippet.Text != "echo one" {
+		t.Errorf("get reply = %s (%v)", replies[0], err)
+	}
+	if batch[2].Error == nil || batch[2].Error.Code != rpcMethodNotFound {
+		t.Errorf("unknown method reply = %s", replies[0])
+	}
+
+	for i, want := range []int{rpcInvalidRequest, rpcParseError} {
+		var reply rpcResponse
+		if err := json.Unmarshal([]byte(replies[i+1]), &reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Error == nil || reply.Error.Code != want || string(reply.ID) != "null" {
+			t.Errorf("reply = %s, want error %d", replies[i+1], want)
+		}
+	}
+}
+
+func TestRPCRejectsUnknownParams(t *testing.T) {
+	useDB(t, filepath.Join(t.TempDir(), "grb.db"))
+	for _, method := range []string{"snippets.search", "snippets.get", "snippets.save", "snippets.render", "clipboard.copy"} {
+		reply := rpcCall(t, method, `{"ref": "x", "query": "x", "text": "x", "bogus": 1}`)
+		if reply.Error == nil || reply.Error.Code != rpcInvalidParams {
+			t.Errorf("%s: reply = %+v, want invalid params", method, reply)
+		}
+	}
+	if reply := rpcCall(t, "snippets.get", `["x"]`); reply.Error == nil || reply.Error.Code != rpcInvalidParams {
+		t.Errorf("positional params: reply = %+v, want invalid params", reply)
+	}
+
+	// As MCP tools, the error comes back in the result.
+	reply := rpcCall(t, "tools/call", `{"name": "snippets_get", "arguments": {"ref": "x", "bogus": 1}}`)
+	var result struct {
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(reply.Result, &result); err != nil || !result.IsError {
+		t.Errorf("tools/call reply = %s, want isError", reply.Result)
+	}
+}
+
+func TestRPCMasksSecrets(t *testing.T) {
+	useDB(t, filepath.Join(t.TempDir(), "grb.db"))
+	if _, err := createSnippet("hunter2 {{user}}", "creds", "pw", true); err != nil {
+		t.Fatal(err)
+	}
+
+	reply := rpcCall(t, "snippets.get", `{"ref": "pw"}`)
+	if strings.Contains(string(reply.Result), "hunter2") {
+		t.Fatalf("get leaks the secret: %s", reply.Result)
+	}
+	var got struct {
+		Snippet      apiSnippet `json:"snippet"`
+		Placeholders []string   `json:"placeholders"`
+	}
+	if err := json.Unmarshal(reply.Result, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Snippet.Text != secretMask || !got.Snippet.Secret || len(got.Placeholders) != 0 {
+		t.Errorf("get = %s, want the masked text and no placeholders", reply.Result)
+	}
+
+	// The text isn't searched; the alias and tags are, and come back masked.
+	var found struct {
+		Snippets []apiSnippet `json:"snippets"`
+		Total    int          `json:"total"`
+	}
+	reply = rpcCall(t, "snippets.search", `{"query": "hunter"}`)
+	if err := json.Unmarshal(reply.Result, &found); err != nil || found.Total != 0 {
+		t.Errorf("search by secret text = %s, want nothing", reply.Result)
+	}
+	reply = rpcCall(t, "snippets.search", `{"query": "creds"}`)
+	if err := json.Unmarshal(reply.Result, &found); err != nil || found.Total != 1 || found.Snippets[0].Text != secretMask {
+		t.Errorf("search by tag = %s, want the masked snippet", reply.Result)
+	}
+
+	if reply := rpcCall(t, "snippets.render", `{"ref": "pw", "vars": {"user": "x"}}`); reply.Error == nil {
+		t.Errorf("render of a secret = %s, want an error", reply.Result)
+	}
+}
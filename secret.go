@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// ------------------ SECRETS ------------------
+
+// Secret snippets are masked wherever grb shows snippets (tables, search,
+// the TUI). Copying one starts a detached 'grb clipboard-clear' that
+// empties the clipboard after clear_after, unless something else has been
+// copied since, and the daemon never captures one. 'grb get' and 'grb run'
+// still use the real text.
+
+const secretMask = "•••••••• (secret)"
+
+// shownText is the text as printed in listings.
+func (s snippet) shownText() string {
+	if s.secret {
+		return secretMask
+	}
+	return s.text
+}
+
+// copyText copies text and, for secrets, schedules the clipboard to be
+// cleared.
+func copyText(text string, secret bool) error {
+	if err := writeClipboard(text); err != nil {
+		return err
+	}
+	if secret {
+		scheduleClipboardClear(text)
+	}
+	return nil
+}
+
+// scheduleClipboardClear starts a background grb that outlives this one.
+// It gets a random key and the secret's HMAC under that key in its
+// environment, so neither the secret nor anything that can be checked
+// against a guess appears in ps, and only this user can read them.
+func scheduleClipboardClear(text string) {
+	after := clearAfter()
+	if after <= 0 {
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	key := randomKey()
+	cmd := exec.Command(exe, "clipboard-clear", after.String())
+	cmd.Env = append(os.Environ(), "GRB_CLEAR_KEY="+hex.EncodeToString(key), "GRB_CLEAR_MAC="+textMAC(key, text))
+	if cmd.Start() == nil {
+		cmd.Process.Release()
+		addPendingClear(text, time.Now().Add(after+time.Minute))
+	}
+}
+
+func randomKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// textMAC is the hex HMAC-SHA256 of text under key.
+func textMAC(key []byte, text string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(text))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// pendingClearsPath lists the secrets waiting to be cleared from the
+// clipboard, so the daemon doesn't capture them: one
+// "<key> <hmac> <unix expiry>" line each, with a random key per line.
+func pendingClearsPath() string {
+	return getDBPath() + ".clearing"
+}
+
+type pendingClear struct {
+	key, mac string
+	until    int64
+}
+
+// pendingClears returns the entries of the pending list that haven't
+// expired.
+func pendingClears() []pendingClear {
+	var pending []pendingClear
+	data, _ := os.ReadFile(pendingClearsPath())
+	now := time.Now().Unix()
+	for _, line := range strings.Split(string(data), "\n") {
+		var p pendingClear
+		if _, err := fmt.Sscanf(line, "%s %s %d", &p.key, &p.mac, &p.until); err == nil && p.until > now {
+			pending = append(pending, p)
+		}
+	}
+	return pending
+}
+
+// addPendingClear records a scheduled clear, dropping expired entries.
+func addPendingClear(text string, until time.Time) {
+	key := randomKey()
+	pending := append(pendingClears(), pendingClear{hex.EncodeToString(key), textMAC(key, text), until.Unix()})
+	var b strings.Builder
+	for _, p := range pending {
+		fmt.Fprintf(&b, "%s %s %d\n", p.key, p.mac, p.until)
+	}
+	os.WriteFile(pendingClearsPath(), []byte(b.String()), 0600)
+}
+
+// isPendingClear reports whether text is waiting to be cleared.
+func isPendingClear(text string) bool {
+	for _, p := range pendingClears() {
+		key, err := hex.DecodeString(p.key)
+		if err == nil && hmac.Equal([]byte(textMAC(key, text)), []byte(p.mac)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSecretText reports whether text is the text of a secret snippet in any
+// vault or is waiting to be cleared from the clipboard.
+func isSecretText(tx *bbolt.Tx, text string) bool {
+	if isPendingClear(text) {
+		return true
+	}
+	for _, name := range listVaults(tx) {
+		c := tx.Bucket(snippetsKey(name)).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if s := parseSnippet(k, v); s.secret && s.text == text {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// clearClipboardLater waits, then empties the clipboard if it still holds
+// the text whose HMAC under GRB_CLEAR_KEY is GRB_CLEAR_MAC.
+func clearClipboardLater(after time.Duration) error {
+	key, err := hex.DecodeString(os.Getenv("GRB_CLEAR_KEY"))
+	want := os.Getenv("GRB_CLEAR_MAC")
+	if err != nil || len(key) == 0 || want == "" {
+		return usagef("GRB_CLEAR_KEY and GRB_CLEAR_MAC must be set")
+	}
+	time.Sleep(after)
+	text, err := readClipboard()
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(textMAC(key, text)), []byte(want)) {
+		return nil
+	}
+	return writeClipboard("")
+}
+
+// toggleSecret marks or unmarks a snippet as secret.
+func toggleSecret(idOrAlias string) error {
+	var s snippet
+	err := db.Update(func(tx *bbolt.Tx) error {
+		var ok bool
+		if s, ok = findSnippet(tx, vault, idOrAlias); !ok {
+			return notFound(idOrAlias)
+		}
+		s.secret = !s.secret
+		return putSnippet(tx.Bucket(snippetsKey(vault)), s.id, s)
+	})
+	if err != nil {
+		return err
+	}
+
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+
+	action := "🔑 Snippet marked secret"
+	if !s.secret {
+		action = "👁 Snippet no longer secret"
+	}
+	fmt.Printf("%s [%s]\n", action, accent(s.id))
+	printSnippetTable([][]string{
+		{accent(s.id), s.shownText(), label(orDash(s.tag)), highlight(orDash(s.alias))},
+	})
+	if s.secret {
+		fmt.Println("💡 Tip: 'grb copy' clears it from the clipboard after clear_after")
+	}
+	return nil
+}
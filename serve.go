@@ -0,0 +1,800 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// ------------------ HTTP API ------------------
+
+// 'grb serve' exposes the library over a small JSON API for scripts,
+// launchers and browser extensions. Every request except /openapi.json
+// needs "Authorization: Bearer <token>"; the token is generated on first
+// run and kept in <db>.token, readable only by its owner. Like the daemon,
+// the server only holds the DB while answering a request, so the TUI and
+// other commands keep working. Requests use the current vault unless they
+// pass ?vault=<name>.
+
+func apiTokenPath() string {
+	return getDBPath() + ".token"
+}
+
+// loadAPIToken returns the API token, creating it if there is none yet or
+// rotate is set. created reports whether a new one was written.
+func loadAPIToken(rotate bool) (token string, created bool, err error) {
+	if data, err := os.ReadFile(apiTokenPath()); err == nil && !rotate {
+		if token = strings.TrimSpace(string(data)); token != "" {
+			return token, false, nil
+		}
+	}
+	token = randomName() + randomName()
+	if err := os.WriteFile(apiTokenPath(), []byte(token+"\n"), 0600); err != nil {
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+// apiSnippet is a snippet as the API sends and lists it.
+type apiSnippet struct {
+	ID       string     `json:"id"`
+	Vault    string     `json:"vault,omitempty"`
+	UUID     string     `json:"uuid,omitempty"`
+	Text     string     `json:"text"`
+	Tags     []string   `json:"tags"`
+	Alias    string     `json:"alias,omitempty"`
+	Pinned   bool       `json:"pinned"`
+	Secret   bool       `json:"secret"`
+	Archived bool       `json:"archived"`
+	UseCount int        `json:"use_count"`
+	LastUsed *time.Time `json:"last_used,omitempty"`
+	Project  bool       `json:"project,omitempty"` // from .grb.yaml (grb rpc only)
+}
+
+// toAPI masks secret text; only the copy endpoint puts it anywhere, and
+// then on this machine's clipboard.
+func toAPI(vault string, s snippet) apiSnippet {
+	tags := splitTags(s.tag)
+	if tags == nil {
+		tags = []string{}
+	}
+	out := apiSnippet{
+		ID: s.id, Vault: vault, UUID: s.uuid, Text: s.shownText(), Tags: tags, Alias: s.alias,
+		Pinned: s.pinned, Secret: s.secret, Archived: s.archived,
+		UseCount: s.useCount,
+	}
+	if s.created != 0 {
+		lastUsed := time.Unix(s.created, 0).UTC()
+		out.LastUsed = &lastUsed
+	}
+	return out
+}
+
+// apiInput is the body of create and update requests. Fields left out of
+// an update keep their value.
+type apiInput struct {
+	Text     *string   `json:"text"`
+	Tags     *[]string `json:"tags"`
+	Alias    *string   `json:"alias"`
+	Pinned   *bool     `json:"pinned"`
+	Secret   *bool     `json:"secret"`
+	Archived *bool     `json:"archived"`
+}
+
+// apply copies the fields set in in onto s. A secret's text sent back as
+// the mask the API returned for it is left unchanged.
+func (in apiInput) apply(s *snippet) {
+	if in.Text != nil && !(s.secret && *in.Text == secretMask) {
+		s.text = *in.Text
+	}
+	if in.Tags != nil {
+		s.tag = strings.Join(splitTags(strings.Join(*in.Tags, ",")), ",")
+	}
+	if in.Alias != nil {
+		s.alias = strings.TrimSpace(*in.Alias)
+	}
+	if in.Pinned != nil {
+		s.pinned = *in.Pinned
+	}
+	if in.Secret != nil {
+		s.secret = *in.Secret
+	}
+	if in.Archived != nil {
+		s.archived = *in.Archived
+	}
+}
+
+type server struct {
+	token string
+	cors  map[string]bool // allowed origins; "*" allows any
+	mu    sync.Mutex      // one request holds the DB at a time
+}
+
+// withDB opens the DB for one request of a long-running server (serve,
+// lsp, rpc) and releases it afterwards, so other grb commands and the TUI
+// keep working. On an encrypted DB the key is fetched for the request and
+// dropped after it, so 'grb lock' and session expiry apply to the next one.
+func withDB(fn func() error) error {
+	if err := acquireDB(); err != nil {
+		return err
+	}
+	defer releaseDB()
+	// 'grb encrypt' may have run since the server started.
+	db.View(func(tx *bbolt.Tx) error {
+		loadEncryption(tx)
+		return nil
+	})
+	if encryption != nil {
+		key, err := requestKey()
+		if err != nil {
+			return err
+		}
+		setSessionKey(key)
+		defer setSessionKey(nil)
+	}
+	return fn()
+}
+
+// passKey caches the key derived from GRB_PASSPHRASE, which stays in the
+// server's environment anyway, so requests don't each pay for scrypt.
+var passKey struct {
+	check string
+	key   []byte
+}
+
+// requestKey returns the key of the daemon's session or GRB_PASSPHRASE.
+func requestKey() ([]byte, error) {
+	if key := daemonKey(); key != nil {
+		return key, nil
+	}
+	pass := os.Getenv("GRB_PASSPHRASE")
+	if pass == "" {
+		return nil, ErrSealed
+	}
+	if passKey.key == nil || passKey.check != encryption.check {
+		key, err := encryption.unlock(pass)
+		if err != nil {
+			return nil, err
+		}
+		passKey.check, passKey.key = encryption.check, key
+	}
+	return passKey.key, nil
+}
+
+// withDB serializes requests, which net/http runs concurrently.
+func (sv *server) withDB(fn func() error) error {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return withDB(fn)
+}
+
+// handler routes the API and wraps it in CORS and auth checks.
+func (sv *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.json", sv.openAPI)
+	mux.HandleFunc("GET /snippets", sv.listSnippets)
+	mux.HandleFunc("POST /snippets", sv.createSnippet)
+	mux.HandleFunc("GET /snippets/{ref}", sv.getSnippet)
+	mux.HandleFunc("PATCH /snippets/{ref}", sv.updateSnippet)
+	mux.HandleFunc("DELETE /snippets/{ref}", sv.deleteSnippet)
+	mux.HandleFunc("POST /snippets/{ref}/copy", sv.copySnippet)
+	mux.HandleFunc("GET /search", sv.search)
+	mux.HandleFunc("GET /stats", sv.stats)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && (sv.cors["*"] || sv.cors[origin]) {
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			h.Set("Access-Control-Max-Age", "600")
+			h.Add("Vary", "Origin")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		if r.URL.Path != "/openapi.json" && !sv.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or wrong bearer token"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// authorized checks the bearer token in constant time.
+func (sv *server) authorized(r *http.Request) bool {
+	got := []byte(r.Header.Get("Authorization"))
+	return subtle.ConstantTimeCompare(got, []byte("Bearer "+sv.token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+// writeError answers with the status matching err's exit code.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch exitCode(err) {
+	case exitUsage:
+		status = http.StatusBadRequest
+	case exitNotFound:
+		status = http.StatusNotFound
+	case exitConflict:
+		status = http.StatusConflict
+	case exitClipboard:
+		status = http.StatusServiceUnavailable
+	case exitLocked:
+		status = http.StatusLocked
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// readInput decodes a request body, rejecting unknown fields.
+func readInput(w http.ResponseWriter, r *http.Request) (apiInput, error) {
+	var in apiInput
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		return in, usagef("invalid JSON body: %v", err)
+	}
+	return in, nil
+}
+
+// requestVault is ?vault=, or the current vault.
+func requestVault(tx *bbolt.Tx, r *http.Request) (string, error) {
+	name := r.URL.Query().Get("vault")
+	if name == "" {
+		name = vault
+	}
+	if !vaultExists(tx, name) {
+		return "", fmt.Errorf("vault %w: %s", ErrNotFound, name)
+	}
+	return name, nil
+}
+
+// collect returns the snippets of the request's vault that keep says
+// match, in id order.
+func collect(tx *bbolt.Tx, r *http.Request, keep func(snippet) bool) ([]apiSnippet, error) {
+	name, err := requestVault(tx, r)
+	if err != nil {
+		return nil, err
+	}
+	archived := r.URL.Query().Get("archived") == "true"
+	tag := r.URL.Query().Get("tag")
+	var out []snippet
+	tx.Bucket(snippetsKey(name)).ForEach(func(k, v []byte) error {
+		s := parseSnippet(k, v)
+		if s.archived == archived && (tag == "" || hasTag(s.tag, tag)) && keep(s) {
+			out = append(out, s)
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return lessID(out[i].id, out[j].id) })
+	list := make([]apiSnippet, len(out))
+	for i, s := range out {
+		list[i] = toAPI(name, s)
+	}
+	return list, nil
+}
+
+func (sv *server) listSnippets(w http.ResponseWriter, r *http.Request) {
+	var list []apiSnippet
+	err := sv.withDB(func() error {
+		return db.View(func(tx *bbolt.Tx) error {
+			var err error
+			list, err = collect(tx, r, func(snippet) bool { return true })
+			return err
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (sv *server) search(w http.ResponseWriter, r *http.Request) {
+	q := strings.ToLower(r.URL.Query().Get("q"))
+	if q == "" {
+		writeError(w, usagef("missing ?q="))
+		return
+	}
+	var list []apiSnippet
+	err := sv.withDB(func() error {
+		return db.View(func(tx *bbolt.Tx) error {
+			var err error
+			list, err = collect(tx, r, func(s snippet) bool {
+				return (!s.secret && strings.Contains(strings.ToLower(s.text), q)) ||
+					strings.Contains(strings.ToLower(s.tag), q) ||
+					strings.Contains(strings.ToLower(s.alias), q)
+			})
+			return err
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (sv *server) getSnippet(w http.ResponseWriter, r *http.Request) {
+	var out apiSnippet
+	err := sv.withDB(func() error {
+		return db.View(func(tx *bbolt.Tx) error {
+			name, err := requestVault(tx, r)
+			if err != nil {
+				return err
+			}
+			s, ok := findSnippet(tx, name, r.PathValue("ref"))
+			if !ok {
+				return notFound(r.PathValue("ref"))
+			}
+			out = toAPI(name, s)
+			return nil
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (sv *server) createSnippet(w http.ResponseWriter, r *http.Request) {
+	in, err := readInput(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if in.Text == nil || strings.TrimSpace(*in.Text) == "" {
+		writeError(w, usagef("text is required"))
+		return
+	}
+	var out apiSnippet
+	err = sv.withDB(func() error {
+		return db.Update(func(tx *bbolt.Tx) error {
+			name, err := requestVault(tx, r)
+			if err != nil {
+				return err
+			}
+			b := tx.Bucket(snippetsKey(name))
+			seq, _ := b.NextSequence()
+			s := snippet{id: fmt.Sprintf("%d", seq), uuid: newUUID(), created: time.Now().Unix()}
+			in.apply(&s)
+			if err := claimAlias(tx, name, s.alias, s.id); err != nil {
+				return err
+			}
+			if err := putSnippet(b, s.id, s); err != nil {
+				return err
+			}
+			out = toAPI(name, s)
+			return nil
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/snippets/"+out.ID)
+	writeJSON(w, http.StatusCreated, out)
+}
+
+func (sv *server) updateSnippet(w http.ResponseWriter, r *http.Request) {
+	in, err := readInput(w, r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var out apiSnippet
+	err = sv.withDB(func() error {
+		return db.Update(func(tx *bbolt.Tx) error {
+			name, err := requestVault(tx, r)
+			if err != nil {
+				return err
+			}
+			s, ok := findSnippet(tx, name, r.PathValue("ref"))
+			if !ok {
+				return notFound(r.PathValue("ref"))
+			}
+			oldAlias := s.alias
+			in.apply(&s)
+			if strings.TrimSpace(s.text) == "" {
+				return usagef("text can't be empty")
+			}
+			if s.alias != oldAlias {
+				if err := claimAlias(tx, name, s.alias, s.id); err != nil {
+					return err
+				}
+				if err := releaseAlias(tx, name, oldAlias, s.id); err != nil {
+					return err
+				}
+			}
+			if err := putSnippet(tx.Bucket(snippetsKey(name)), s.id, s); err != nil {
+				return err
+			}
+			out = toAPI(name, s)
+			return nil
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (sv *server) deleteSnippet(w http.ResponseWriter, r *http.Request) {
+	err := sv.withDB(func() error {
+		return db.Update(func(tx *bbolt.Tx) error {
+			name, err := requestVault(tx, r)
+			if err != nil {
+				return err
+			}
+			s, ok := findSnippet(tx, name, r.PathValue("ref"))
+			if !ok {
+				return notFound(r.PathValue("ref"))
+			}
+			return dropSnippet(tx, name, s)
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// copySnippet copies a snippet to the clipboard of the machine grb serve
+// runs on, counting it as a use.
+func (sv *server) copySnippet(w http.ResponseWriter, r *http.Request) {
+	var out apiSnippet
+	err := sv.withDB(func() error {
+		return db.Update(func(tx *bbolt.Tx) error {
+			name, err := requestVault(tx, r)
+			if err != nil {
+				return err
+			}
+			s, ok := findSnippet(tx, name, r.PathValue("ref"))
+			if !ok {
+				return notFound(r.PathValue("ref"))
+			}
+			if err := copyText(s.text, s.secret); err != nil {
+				return err
+			}
+			s.useCount++
+			s.created = time.Now().Unix()
+			if err := logEvent(tx, eventCopy, name, s.id); err != nil {
+				return err
+			}
+			if err := putSnippet(tx.Bucket(snippetsKey(name)), s.id, s); err != nil {
+				return err
+			}
+			out = toAPI(name, s)
+			return nil
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// apiStats is the body of GET /stats.
+type apiStats struct {
+	Vault    string         `json:"vault"`
+	Total    int            `json:"total"`
+	Tags     []apiTagCount  `json:"tags"`
+	Since    string         `json:"since"`
+	Uses     int            `json:"uses"`
+	Captures int            `json:"captures"`
+	Step     string         `json:"step"` // "day" or "week"
+	Activity []int          `json:"activity"`
+	Top      []apiUsedCount `json:"top"`
+}
+
+type apiTagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type apiUsedCount struct {
+	ID    string `json:"id"`
+	Alias string `json:"alias,omitempty"`
+	Uses  int    `json:"uses"`
+}
+
+func (sv *server) stats(w http.ResponseWriter, r *http.Request) {
+	since := 30 * 24 * time.Hour
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		if since, err = parseSince(v); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	out := apiStats{Since: formatPeriod(since), Tags: []apiTagCount{}, Top: []apiUsedCount{}}
+	err := sv.withDB(func() error {
+		return db.View(func(tx *bbolt.Tx) error {
+			name, err := requestVault(tx, r)
+			if err != nil {
+				return err
+			}
+			out.Vault = name
+			b := tx.Bucket(snippetsKey(name))
+			counts := map[string]int{}
+			b.ForEach(func(k, v []byte) error {
+				out.Total++
+				for _, t := range splitTags(parseSnippet(k, v).tag) {
+					counts[t]++
+				}
+				return nil
+			})
+			for t, n := range counts {
+				out.Tags = append(out.Tags, apiTagCount{t, n})
+			}
+			sort.Slice(out.Tags, func(i, j int) bool {
+				if out.Tags[i].Count != out.Tags[j].Count {
+					return out.Tags[i].Count > out.Tags[j].Count
+				}
+				return out.Tags[i].Tag < out.Tags[j].Tag
+			})
+
+			u := summarizeUsage(tx, name, since)
+			out.Uses, out.Captures, out.Activity = u.uses, u.captures, u.buckets
+			out.Step = "day"
+			if u.step > 24*time.Hour {
+				out.Step = "week"
+			}
+			for _, id := range u.top(10) {
+				used := apiUsedCount{ID: id, Uses: u.counts[id]}
+				if v := b.Get([]byte(id)); v != nil {
+					used.Alias = parseSnippet([]byte(id), v).alias
+				}
+				out.Top = append(out.Top, used)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (sv *server) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(openAPIDoc))
+}
+
+// serveAPI runs the HTTP API on addr until interrupted.
+func serveAPI(addr string, cors []string, rotate bool) error {
+	token, created, err := loadAPIToken(rotate)
+	if err != nil {
+		return err
+	}
+	sv := &server{token: token, cors: map[string]bool{}}
+	for _, origin := range cors {
+		sv.cors[strings.TrimRight(origin, "/")] = true
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	releaseDB()
+
+	say(theme.highlight, "🌐 grb API listening on http://%s", ln.Addr())
+	fmt.Println("─────────────────────────────────────────────")
+	if created {
+		say(theme.success, "🔑 New API token written to %s", apiTokenPath())
+	}
+	fmt.Printf("Authorization: Bearer %s\n", token)
+	fmt.Printf("💡 Tip: The API is described at http://%s/openapi.json\n", ln.Addr())
+	if host, _, _ := net.SplitHostPort(ln.Addr().String()); !net.ParseIP(host).IsLoopback() {
+		say(theme.highlight, "⚠ Listening beyond this machine: anyone with the token can read your snippets")
+	}
+
+	srv := &http.Server{Handler: sv.handler(), ReadHeaderTimeout: 10 * time.Second}
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// openAPIDoc describes the API for clients and code generators.
+const openAPIDoc = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "grb API",
+    "version": "1.0.0",
+    "description": "JSON API served by 'grb serve'. Every path except /openapi.json needs the bearer token printed on start. Paths act on the current vault unless ?vault= is given."
+  },
+  "servers": [{"url": "/"}],
+  "security": [{"bearer": []}],
+  "paths": {
+    "/snippets": {
+      "get": {
+        "summary": "List snippets",
+        "parameters": [
+          {"$ref": "#/components/parameters/vault"},
+          {"$ref": "#/components/parameters/tag"},
+          {"$ref": "#/components/parameters/archived"}
+        ],
+        "responses": {
+          "200": {"description": "Snippets in id order", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Snippet"}}}}},
+          "401": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "summary": "Save a snippet",
+        "parameters": [{"$ref": "#/components/parameters/vault"}],
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/SnippetInput"}}}},
+        "responses": {
+          "201": {"$ref": "#/components/responses/Snippet"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/snippets/{ref}": {
+      "parameters": [
+        {"$ref": "#/components/parameters/ref"},
+        {"$ref": "#/components/parameters/vault"}
+      ],
+      "get": {
+        "summary": "Get a snippet by id or alias",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Snippet"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "patch": {
+        "summary": "Change a snippet; fields left out keep their value",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"$ref": "#/components/schemas/SnippetInput"}}}},
+        "responses": {
+          "200": {"$ref": "#/components/responses/Snippet"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "summary": "Delete a snippet",
+        "responses": {
+          "204": {"description": "Deleted"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/snippets/{ref}/copy": {
+      "parameters": [
+        {"$ref": "#/components/parameters/ref"},
+        {"$ref": "#/components/parameters/vault"}
+      ],
+      "post": {
+        "summary": "Copy a snippet to the clipboard of the machine running grb serve",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Snippet"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "503": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/search": {
+      "get": {
+        "summary": "Search text, tags and aliases",
+        "parameters": [
+          {"name": "q", "in": "query", "required": true, "schema": {"type": "string"}},
+          {"$ref": "#/components/parameters/vault"},
+          {"$ref": "#/components/parameters/tag"},
+          {"$ref": "#/components/parameters/archived"}
+        ],
+        "responses": {
+          "200": {"description": "Matching snippets in id order", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Snippet"}}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/stats": {
+      "get": {
+        "summary": "Library totals and usage over a period",
+        "parameters": [
+          {"$ref": "#/components/parameters/vault"},
+          {"name": "since", "in": "query", "description": "Period such as 7d, 4w or 12h", "schema": {"type": "string", "default": "30d"}}
+        ],
+        "responses": {
+          "200": {"description": "Stats", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Stats"}}}},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "summary": "This document",
+        "security": [],
+        "responses": {"200": {"description": "OpenAPI document"}}
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearer": {"type": "http", "scheme": "bearer"}
+    },
+    "parameters": {
+      "ref": {"name": "ref", "in": "path", "required": true, "description": "Snippet id or alias", "schema": {"type": "string"}},
+      "vault": {"name": "vault", "in": "query", "description": "Vault name; defaults to the current vault", "schema": {"type": "string"}},
+      "tag": {"name": "tag", "in": "query", "description": "Only snippets with this tag", "schema": {"type": "string"}},
+      "archived": {"name": "archived", "in": "query", "description": "Show archived snippets instead of the others", "schema": {"type": "boolean", "default": false}}
+    },
+    "responses": {
+      "Snippet": {"description": "The snippet", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Snippet"}}}},
+      "Error": {"description": "Error", "content": {"application/json": {"schema": {"type": "object", "properties": {"error": {"type": "string"}}, "required": ["error"]}}}}
+    },
+    "schemas": {
+      "Snippet": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"},
+          "vault": {"type": "string"},
+          "uuid": {"type": "string"},
+          "text": {"type": "string", "description": "Masked for secret snippets; sending the mask back in an update keeps the text"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "alias": {"type": "string"},
+          "pinned": {"type": "boolean"},
+          "secret": {"type": "boolean"},
+          "archived": {"type": "boolean"},
+          "use_count": {"type": "integer"},
+          "last_used": {"type": "string", "format": "date-time"}
+        },
+        "required": ["id", "vault", "uuid", "text", "tags", "pinned", "secret", "archived", "use_count", "last_used"]
+      },
+      "SnippetInput": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "text": {"type": "string", "description": "Required when saving"},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "alias": {"type": "string"},
+          "pinned": {"type": "boolean"},
+          "secret": {"type": "boolean"},
+          "archived": {"type": "boolean"}
+        }
+      },
+      "Stats": {
+        "type": "object",
+        "properties": {
+          "vault": {"type": "string"},
+          "total": {"type": "integer"},
+          "tags": {"type": "array", "items": {"type": "object", "properties": {"tag": {"type": "string"}, "count": {"type": "integer"}}}},
+          "since": {"type": "string"},
+          "uses": {"type": "integer"},
+          "captures": {"type": "integer"},
+          "step": {"type": "string", "enum": ["day", "week"]},
+          "activity": {"type": "array", "items": {"type": "integer"}, "description": "Uses per step, oldest first"},
+          "top": {"type": "array", "items": {"type": "object", "properties": {"id": {"type": "string"}, "alias": {"type": "string"}, "uses": {"type": "integer"}}}}
+        }
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ------------------ DAEMON SOCKET ------------------
+
+// The daemon listens on a loopback port and pushes one line per change
+// ("changed <id>") to every connected client. The address is written to
+// <db>.addr next to the DB so clients can find it, one per database,
+// followed by a random token. Clients send "<token> <request>" lines to
+// manage the session of an encrypted DB; the file is only readable by its
+// owner, so other local users can't ask for the key.
+
+func daemonAddrPath() string {
+	return getDBPath() + ".addr"
+}
+
+type notifier struct {
+	ln      net.Listener
+	token   string
+	mu      sync.Mutex
+	conns   map[net.Conn]bool
+	expires *time.Timer // ends the unlocked session
+}
+
+// startNotifier starts accepting subscribers and publishes the listen
+// address. The address file is removed again when the daemon exits.
+func startNotifier() (*notifier, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	token := randomName()
+	if err := os.WriteFile(daemonAddrPath(), []byte(ln.Addr().String()+" "+token), 0600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	n := &notifier{ln: ln, token: token, conns: map[net.Conn]bool{}}
+	go n.accept()
+
+	// Ctrl+C skips deferred calls, so clean up the address file here.
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		n.close()
+		releaseDB()
+		os.Exit(0)
+	}()
+	return n, nil
+}
+
+func (n *notifier) accept() {
+	for {
+		conn, err := n.ln.Accept()
+		if err != nil {
+			return
+		}
+		n.mu.Lock()
+		n.conns[conn] = true
+		n.mu.Unlock()
+		go n.serve(conn)
+	}
+}
+
+// serve answers session requests from one client:
+//
+//	unlock <hex-key> <seconds>   cache the key until the session expires
+//	lock                         forget the key
+//	key                          reply "key <hex-key>" or "locked"
+func (n *notifier) serve(conn net.Conn) {
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 || fields[0] != n.token {
+			n.reply(conn, "denied")
+			continue
+		}
+		switch args := fields[2:]; fields[1] {
+		case "unlock":
+			if len(args) != 2 {
+				n.reply(conn, "bad request")
+				continue
+			}
+			key, err := hex.DecodeString(args[0])
+			secs, _ := strconv.Atoi(args[1])
+			if err != nil || len(key) == 0 || secs <= 0 {
+				n.reply(conn, "bad request")
+				continue
+			}
+			n.unlock(key, time.Duration(secs)*time.Second)
+			n.reply(conn, "ok")
+		case "lock":
+			n.unlock(nil, 0)
+			n.reply(conn, "ok")
+		case "key":
+			if key := sessionKey(); key != nil {
+				n.reply(conn, "key "+hex.EncodeToString(key))
+			} else {
+				n.reply(conn, "locked")
+			}
+		default:
+			n.reply(conn, "bad request")
+		}
+	}
+}
+
+// unlock caches key for ttl, or ends the session when key is nil.
+func (n *notifier) unlock(key []byte, ttl time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.expires != nil {
+		n.expires.Stop()
+		n.expires = nil
+	}
+	setSessionKey(key)
+	if key == nil {
+		say(theme.highlight, "🔒 Session locked")
+		return
+	}
+	n.expires = time.AfterFunc(ttl, func() { n.unlock(nil, 0) })
+	say(theme.success, "🔓 Session unlocked for %s", ttl)
+}
+
+func (n *notifier) reply(conn net.Conn, line string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(time.Second))
+	conn.Write([]byte(line + "\n"))
+}
+
+// broadcast sends line to every subscriber, dropping those that went away.
+// It is a no-op on a nil notifier so callers need not check startup errors.
+func (n *notifier) broadcast(line string) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for conn := range n.conns {
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		if _, err := conn.Write([]byte(line + "\n")); err != nil {
+			conn.Close()
+			delete(n.conns, conn)
+		}
+	}
+}
+
+func (n *notifier) close() {
+	n.ln.Close()
+	n.mu.Lock()
+	for conn := range n.conns {
+		conn.Close()
+	}
+	n.mu.Unlock()
+	os.Remove(daemonAddrPath())
+}
+
+// daemonAddr returns the running daemon's address and token.
+func daemonAddr() (string, string) {
+	data, err := os.ReadFile(daemonAddrPath())
+	if err != nil {
+		return "", ""
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return "", ""
+	}
+	return fields[0], fields[1]
+}
+
+// dialDaemon connects to a running daemon, or returns nil if none is
+// listening (including a stale address file left by a crash).
+func dialDaemon() net.Conn {
+	addr, _ := daemonAddr()
+	if addr == "" {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", addr, 500*time.Millisecond)
+	if err != nil {
+		return nil
+	}
+	return conn
+}
+
+// daemonRequest sends one session request and returns the reply,
+// skipping change notifications sent in the meantime.
+func daemonRequest(request ...string) (string, error) {
+	conn := dialDaemon()
+	if conn == nil {
+		return "", fmt.Errorf("no grb daemon is running to hold the session; start 'grb daemon' or set GRB_PASSPHRASE")
+	}
+	defer conn.Close()
+	_, token := daemonAddr()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Write([]byte(token + " " + strings.Join(request, " ") + "\n")); err != nil {
+		return "", err
+	}
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		if !strings.HasPrefix(sc.Text(), "changed ") {
+			return sc.Text(), nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("the daemon closed the connection")
+}
+
+// subscribeDaemon calls onChange for every change line the daemon sends
+// until the connection closes.
+func subscribeDaemon(conn net.Conn, onChange func(id string)) {
+	defer conn.Close()
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		if id, ok := strings.CutPrefix(sc.Text(), "changed "); ok {
+			onChange(id)
+		}
+	}
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.etcd.io/bbolt"
+	"gopkg.in/yaml.v3"
+)
+
+// ------------------ GIT SYNC ------------------
+
+// The library is mirrored into a git working tree next to the DB, one
+// <vault>/<name>.md file per snippet: YAML front-matter with the metadata,
+// then the text byte for byte. The "sync" bucket maps "<vault>/<id>" to
+// the snippet's file and the hash of the content last written or applied,
+// so a sync only exports local changes and only applies remote ones.
+// Use counts and timestamps stay local.
+
+// ErrSyncConflict means a sync stopped on changes that need a person.
+var ErrSyncConflict = errors.New("sync conflict")
+
+// syncPlaintext lets an encrypted database sync anyway (--allow-plaintext).
+// Both sync paths write snippet text as is, and the other machines can't
+// open this database's ciphertext, so by default they refuse.
+var syncPlaintext bool
+
+// checkPlaintextSync refuses to copy an encrypted library out in
+// plaintext unless --allow-plaintext was given.
+func checkPlaintextSync() error {
+	if encryption != nil && !syncPlaintext {
+		return usagef("the database is encrypted, but sync copies snippet text in plaintext; pass --allow-plaintext to sync anyway")
+	}
+	return nil
+}
+
+func syncDir() string {
+	return getDBPath() + ".sync"
+}
+
+type syncMeta struct {
+	Tag      string `yaml:"tag,omitempty"`
+	Alias    string `yaml:"alias,omitempty"`
+	Pinned   bool   `yaml:"pinned,omitempty"`
+	Secret   bool   `yaml:"secret,omitempty"`
+	Archived bool   `yaml:"archived,omitempty"`
+}
+
+// renderSyncFile returns the file content for s.
+func renderSyncFile(s snippet) []byte {
+	meta, _ := yaml.Marshal(syncMeta{Tag: s.tag, Alias: s.alias, Pinned: s.pinned, Secret: s.secret, Archived: s.archived})
+	return []byte("---\n" + string(meta) + "---\n" + s.text)
+}
+
+// parseSyncFile is the inverse of renderSyncFile.
+func parseSyncFile(data []byte) (snippet, error) {
+	raw := string(data)
+	if !strings.HasPrefix(raw, "---\n") {
+		return snippet{}, fmt.Errorf("missing front-matter")
+	}
+	end := strings.Index(raw[4:], "\n---\n")
+	if end < 0 {
+		return snippet{}, fmt.Errorf("unterminated front-matter")
+	}
+	var meta syncMeta
+	if err := yaml.Unmarshal([]byte(raw[4:4+end+1]), &meta); err != nil {
+		return snippet{}, err
+	}
+	if err := validateAlias(meta.Alias); err != nil {
+		return snippet{}, err
+	}
+	return snippet{text: raw[4+end+5:], tag: meta.Tag, alias: meta.Alias, pinned: meta.Pinned, secret: meta.Secret, archived: meta.Archived}, nil
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func randomName() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// git runs a git command in dir and returns its output.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return out.String(), fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// gitAs runs a git command that creates commits, naming grb as the
+// author when git has no identity configured.
+func gitAs(dir string, args ...string) error {
+	if email, _ := git(dir, "config", "user.email"); email == "" {
+		host, _ := os.Hostname()
+		args = append([]string{"-c", "user.name=grb", "-c", "user.email=grb@" + host}, args...)
+	}
+	_, err := git(dir, args...)
+	return err
+}
+
+// unmergedFiles lists files git still has conflicts for.
+func unmergedFiles(dir string) []string {
+	out, _ := git(dir, "diff", "--name-only", "--diff-filter=U")
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+func conflictError(dir string, files []string) error {
+	return fmt.Errorf("%w in %s:\n  %s\nresolve them, 'git add' the files and run 'grb sync' again",
+		ErrSyncConflict, dir, strings.Join(files, "\n  "))
+}
+
+type syncReport struct {
+	exported, removed       int
+	added, updated, deleted int
+	conflicts               []string
+}
+
+// initSync clones repo (a path or a remote URL) as the sync working tree
+// and runs a first sync.
+func initSync(repo string) error {
+	if err := checkPlaintextSync(); err != nil {
+		return err
+	}
+	dir := syncDir()
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("sync is already set up in %s", dir)
+	}
+	if _, err := git(".", "clone", "-q", repo, dir); err != nil {
+		return err
+	}
+	// Keep snippet bytes exact on every platform.
+	attributes := filepath.Join(dir, ".gitattributes")
+	if !isFile(attributes) {
+		if err := os.WriteFile(attributes, []byte("*.md -text\n"), 0644); err != nil {
+			return err
+		}
+	}
+	return runSync()
+}
+
+// runSync exports local changes, commits them, pulls, applies remote
+// changes and pushes.
+func runSync() error {
+	if err := checkPlaintextSync(); err != nil {
+		return err
+	}
+	dir := syncDir()
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return usagef("sync is not set up; run 'grb sync init <repo-path-or-remote>'")
+	}
+	if files := unmergedFiles(dir); len(files) > 0 {
+		return conflictError(dir, files)
+	}
+
+	var r syncReport
+	if err := db.Update(func(tx *bbolt.Tx) error { return exportSync(tx, dir, &r) }); err != nil {
+		return err
+	}
+
+	// The DB is not needed while git talks to the remote.
+	releaseDB()
+
+	if _, err := git(dir, "add", "-A"); err != nil {
+		return err
+	}
+	status, err := git(dir, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	_, mergeErr := git(dir, "rev-parse", "-q", "--verify", "MERGE_HEAD")
+	if status != "" || mergeErr == nil {
+		host, _ := os.Hostname()
+		if err := gitAs(dir, "commit", "-q", "--no-edit", "-m", "grb sync from "+host); err != nil {
+			return err
+		}
+	}
+
+	remote, _ := git(dir, "remote")
+	branch, _ := git(dir, "symbolic-ref", "--short", "HEAD")
+	if remote != "" && branch != "" {
+		if _, err := git(dir, "ls-remote", "--exit-code", "--heads", "origin", branch); err == nil {
+			if err := gitAs(dir, "pull", "-q", "--no-rebase", "--no-edit", "origin", branch); err != nil {
+				if files := unmergedFiles(dir); len(files) > 0 {
+					return conflictError(dir, files)
+				}
+				return err
+			}
+		}
+	}
+
+	if err := acquireDB(); err != nil {
+		return err
+	}
+	if err := db.Update(func(tx *bbolt.Tx) error { return importSync(tx, dir, &r) }); err != nil {
+		return err
+	}
+
+	if remote != "" {
+		if _, err := git(dir, "push", "-q", "origin", "HEAD"); err != nil {
+			return err
+		}
+	}
+
+	say(theme.success, "🔄 Synced with %s", dir)
+	fmt.Printf("   ⬆ %d exported, %d removed\n", r.exported, r.removed)
+	fmt.Printf("   ⬇ %d added, %d updated, %d deleted\n", r.added, r.updated, r.deleted)
+	if len(r.conflicts) > 0 {
+		return fmt.Errorf("%w: %d snippet(s) not applied:\n  %s", ErrSyncConflict, len(r.conflicts), strings.Join(r.conflicts, "\n  "))
+	}
+	return nil
+}
+
+// exportSync writes snippets changed since the last sync and removes the
+// files of deleted ones.
+func exportSync(tx *bbolt.Tx, dir string, r *syncReport) error {
+	state, err := tx.CreateBucketIfNotExists([]byte("sync"))
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, name := range listVaults(tx) {
+		err := tx.Bucket(snippetsKey(name)).ForEach(func(k, v []byte) error {
+			key := name + "/" + string(k)
+			seen[key] = true
+
+			content := renderSyncFile(parseSnippet(k, v))
+			hash := contentHash(content)
+			file, old := syncEntry(state, key)
+			if hash == old {
+				return nil
+			}
+			if file == "" {
+				file = name + "/" + randomName() + ".md"
+			}
+			path := filepath.Join(dir, filepath.FromSlash(file))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				return err
+			}
+			r.exported++
+			return state.Put([]byte(key), joinRecord(file, hash))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var gone []string
+	state.ForEach(func(k, _ []byte) error {
+		if !seen[string(k)] {
+			gone = append(gone, string(k))
+		}
+		return nil
+	})
+	for _, key := range gone {
+		file, _ := syncEntry(state, key)
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(file))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := state.Delete([]byte(key)); err != nil {
+			return err
+		}
+		r.removed++
+	}
+	return nil
+}
+
+// importSync applies files added, changed or deleted by the remote.
+// Snippets also changed locally since the last sync are reported as
+// conflicts and left alone.
+func importSync(tx *bbolt.Tx, dir string, r *syncReport) error {
+	state := tx.Bucket([]byte("sync"))
+	byFile := map[string]string{}
+	state.ForEach(func(k, v []byte) error {
+		file, _ := syncEntry(state, string(k))
+		byFile[file] = string(k)
+		return nil
+	})
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*.md"))
+	sort.Strings(files)
+	present := map[string]bool{}
+	for _, path := range files {
+		rel, _ := filepath.Rel(dir, path)
+		file := filepath.ToSlash(rel)
+		present[file] = true
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		hash := contentHash(data)
+		key, known := byFile[file]
+		if known {
+			if _, old := syncEntry(state, key); old == hash {
+				continue
+			}
+		}
+
+		incoming, err := parseSyncFile(data)
+		if err != nil {
+			r.conflicts = append(r.conflicts, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		name := strings.SplitN(file, "/", 2)[0]
+		if err := validateVault(name); err != nil {
+			r.conflicts = append(r.conflicts, fmt.Sprintf("%s: %v", file, err))
+			continue
+		}
+		if _, err := tx.CreateBucketIfNotExists(snippetsKey(name)); err != nil {
+			return err
+		}
+		b := tx.Bucket(snippetsKey(name))
+
+		var local snippet
+		exists := false
+		if known {
+			id := strings.SplitN(key, "/", 2)[1]
+			if v := b.Get([]byte(id)); v != nil {
+				local, exists = parseSnippet([]byte(id), v), true
+			}
+		}
+
+		if exists {
+			if _, old := syncEntry(state, key); contentHash(renderSyncFile(local)) != old {
+				r.conflicts = append(r.conflicts, fmt.Sprintf("%s: changed here and on the remote", file))
+				continue
+			}
+			updated := local
+			updated.text, updated.tag, updated.pinned, updated.secret = incoming.text, incoming.tag, incoming.pinned, incoming.secret
+			updated.archived = incoming.archived
+			if incoming.alias != local.alias {
+				if err := claimAlias(tx, name, incoming.alias, local.id); err != nil {
+					r.conflicts = append(r.conflicts, fmt.Sprintf("%s: %v", file, err))
+					continue
+				}
+				if err := releaseAlias(tx, name, local.alias, local.id); err != nil {
+					return err
+				}
+				updated.alias = incoming.alias
+			}
+			if err := putSnippet(b, local.id, updated); err != nil {
+				return err
+			}
+			r.updated++
+		} else {
+			seq, _ := b.NextSequence()
+			s := incoming
+			s.id = fmt.Sprintf("%d", seq)
+			if err := claimAlias(tx, name, s.alias, s.id); err != nil {
+				r.conflicts = append(r.conflicts, fmt.Sprintf("%s: %v", file, err))
+				continue
+			}
+			if err := putSnippet(b, s.id, s); err != nil {
+				return err
+			}
+			if known {
+				state.Delete([]byte(key))
+			}
+			key = name + "/" + s.id
+			r.added++
+		}
+		if err := state.Put([]byte(key), joinRecord(file, hash)); err != nil {
+			return err
+		}
+	}
+
+	// Files deleted on the remote.
+	for file, key := range byFile {
+		if present[file] {
+			continue
+		}
+		parts := strings.SplitN(key, "/", 2)
+		b := tx.Bucket(snippetsKey(parts[0]))
+		if b != nil {
+			if v := b.Get([]byte(parts[1])); v != nil {
+				local := parseSnippet([]byte(parts[1]), v)
+				if _, old := syncEntry(state, key); contentHash(renderSyncFile(local)) != old {
+					r.conflicts = append(r.conflicts, fmt.Sprintf("%s: deleted on the remote but changed here", file))
+					continue
+				}
+				if err := dropSnippet(tx, parts[0], local); err != nil {
+					return err
+				}
+				r.deleted++
+			}
+		}
+		if err := state.Delete([]byte(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncEntry returns the file and content hash recorded for key.
+func syncEntry(state *bbolt.Bucket, key string) (string, string) {
+	v := state.Get([]byte(key))
+	if v == nil {
+		return "", ""
+	}
+	fields := splitRecord(v)
+	if len(fields) < 2 {
+		return "", ""
+	}
+	return fields[0], fields[1]
+}
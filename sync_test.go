@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"go.etcd.io/bbolt"
+)
+
+// useDB makes path the database the package works on, as --db would.
+func useDB(t *testing.T, path string) {
+	t.Helper()
+	releaseDB()
+	setSessionKey(nil)
+	active.DB = path
+	if err := initDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(releaseDB)
+	if err := openSession(&cobra.Command{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// editStored applies change to the snippet with alias in the current
+// vault and stores it.
+func editStored(t *testing.T, alias string, change func(*snippet)) {
+	t.Helper()
+	err := db.Update(func(tx *bbolt.Tx) error {
+		s, ok := findSnippet(tx, vault, alias)
+		if !ok {
+			t.Fatalf("no snippet %q", alias)
+		}
+		change(&s)
+		return putSnippet(tx.Bucket(snippetsKey(vault)), s.id, s)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// library returns the synced fields of every snippet, sorted.
+func library(t *testing.T) []map[string]string {
+	t.Helper()
+	var out []map[string]string
+	db.View(func(tx *bbolt.Tx) error {
+		for _, name := range listVaults(tx) {
+			tx.Bucket(snippetsKey(name)).ForEach(func(k, v []byte) error {
+				fields := parseSnippet(k, v).fieldValues()
+				fields["vault"] = name
+				out = append(out, fields)
+				return nil
+			})
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i]["text"] < out[j]["text"] })
+	return out
+}
+
+func TestGitSyncConverges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	remote := filepath.Join(dir, "remote.git")
+	if _, err := git(dir, "init", "-q", "--bare", remote); err != nil {
+		t.Fatal(err)
+	}
+	a, b := filepath.Join(dir, "a", "grb.db"), filepath.Join(dir, "b", "grb.db")
+
+	useDB(t, a)
+	if _, err := createSnippet("echo one", "ops", "one", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := initSync(remote); err != nil {
+		t.Fatal(err)
+	}
+
+	useDB(t, b)
+	if err := initSync(remote); err != nil {
+		t.Fatal(err)
+	}
+	if got := library(t); len(got) != 1 || got[0]["text"] != "echo one" {
+		t.Fatalf("b after first sync = %v", got)
+	}
+
+	// Both machines change a different field of the same snippet.
+	editStored(t, "one", func(s *snippet) { s.tag = "ops,b" })
+	if _, err := createSnippet("echo two", "", "two", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := runSync(); err != nil {
+		t.Fatal(err)
+	}
+
+	useDB(t, a)
+	editStored(t, "one", func(s *snippet) { s.text = "echo uno" })
+	if err := runSync(); err != nil {
+		t.Fatal(err)
+	}
+	libA := library(t)
+
+	useDB(t, b)
+	if err := runSync(); err != nil {
+		t.Fatal(err)
+	}
+	libB := library(t)
+
+	if !reflect.DeepEqual(libA, libB) {
+		t.Fatalf("libraries differ:\na: %v\nb: %v", libA, libB)
+	}
+	if len(libA) != 2 || libA[0]["text"] != "echo two" || libA[1]["text"] != "echo uno" || libA[1]["tag"] != "ops,b" {
+		t.Fatalf("library = %v, want both edits of 'one' and 'two'", libA)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ------------------ TEMPLATES ------------------
+
+// Snippets may contain placeholders such as {{host}} or {{port:8080}}
+// (with a default). They are filled in by 'grb get --var' and 'grb run'.
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*(?::([^}]*))?\}\}`)
+
+// placeholders returns the distinct placeholder names in text, in order of
+// first appearance.
+func placeholders(text string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range placeholderRe.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// renderTemplate substitutes vars into text. Placeholders without a value
+// fall back to their default; any left unfilled are returned as an error.
+func renderTemplate(text string, vars map[string]string) (string, error) {
+	var missing []string
+	seen := map[string]bool{}
+	out := placeholderRe.ReplaceAllStringFunc(text, func(match string) string {
+		m := placeholderRe.FindStringSubmatch(match)
+		if v, ok := vars[m[1]]; ok {
+			return v
+		}
+		if strings.Contains(match, ":") {
+			return m[2]
+		}
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			missing = append(missing, m[1])
+		}
+		return match
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing value for %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// parseVars turns name=value pairs from --var flags into a map.
+func parseVars(pairs []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, p := range pairs {
+		name, value, ok := strings.Cut(p, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --var %q, expected name=value", p)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// bindArgs maps command-line arguments onto the placeholders of text:
+// numeric placeholders ({{1}}, {{2}}) take the argument at that position
+// and named ones take the remaining arguments in order. Arguments left over
+// are returned so they can be appended to the command.
+func bindArgs(text string, args []string, vars map[string]string) []string {
+	used := make([]bool, len(args))
+	var named []string
+	for _, name := range placeholders(text) {
+		if _, ok := vars[name]; ok {
+			continue
+		}
+		var n int
+		if _, err := fmt.Sscanf(name, "%d", &n); err == nil && fmt.Sprint(n) == name {
+			if n >= 1 && n <= len(args) {
+				vars[name] = args[n-1]
+				used[n-1] = true
+			}
+			continue
+		}
+		named = append(named, name)
+	}
+
+	next := 0
+	for _, name := range named {
+		for next < len(args) && used[next] {
+			next++
+		}
+		if next == len(args) {
+			break
+		}
+		vars[name] = args[next]
+		used[next] = true
+	}
+
+	var rest []string
+	for i, a := range args {
+		if !used[i] {
+			rest = append(rest, a)
+		}
+	}
+	return rest
+}
+
+// renderCommand builds the shell command 'grb run' executes: args are
+// bound with bindArgs, every value from args or vars is quoted with
+// shellQuote so it stays one word, and leftover args are appended.
+// Defaults written in the snippet itself are used as is.
+func renderCommand(text string, args []string, vars map[string]string) (string, error) {
+	rest := bindArgs(text, args, vars)
+	quoted := make(map[string]string, len(vars))
+	for name, v := range vars {
+		quoted[name] = shellQuote(v)
+	}
+	command, err := renderTemplate(text, quoted)
+	if err != nil {
+		return "", err
+	}
+	for _, a := range rest {
+		command += " " + shellQuote(a)
+	}
+	return command, nil
+}
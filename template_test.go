@@ -0,0 +1,48 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestRenderCommandQuotesValues(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("quoting is for sh")
+	}
+	tests := []struct {
+		name string
+		text string
+		args []string
+		vars map[string]string
+		want string
+	}{
+		{"bound arg", "echo {{name}}", []string{"a b; echo INJECTED"}, nil, "echo 'a b; echo INJECTED'"},
+		{"numbered arg", "echo {{1}}", []string{"$(id)"}, nil, "echo '$(id)'"},
+		{"var", "echo {{name}}", nil, map[string]string{"name": "x`id`"}, "echo 'x`id`'"},
+		{"single quote", "echo {{name}}", []string{"it's"}, nil, `echo 'it'\''s'`},
+		{"plain word", "echo {{name}}", []string{"hello"}, nil, "echo hello"},
+		{"default kept", "ls {{flags:-la | head}}", nil, nil, "ls -la | head"},
+		{"leftover", "echo {{1}}", []string{"a", "b c"}, nil, "echo a 'b c'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := map[string]string{}
+			for k, v := range tt.vars {
+				vars[k] = v
+			}
+			got, err := renderCommand(tt.text, tt.args, vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("renderCommand(%q, %q) = %q, want %q", tt.text, tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCommandMissingValue(t *testing.T) {
+	if _, err := renderCommand("ssh {{host}}", nil, map[string]string{}); err == nil {
+		t.Fatal("expected an error for a missing placeholder")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/fatih/color"
+	"github.com/muesli/termenv"
+)
+
+// ------------------ THEMES ------------------
+
+// palette assigns a color to each role used in tables and the TUI.
+type palette struct {
+	accent    *color.Color // headings, ids, key hints
+	highlight *color.Color // aliases, pinned items, warnings
+	label     *color.Color // tags
+	success   *color.Color
+	danger    *color.Color
+}
+
+var themes = map[string]palette{
+	"dark": {
+		accent:    color.New(color.FgCyan),
+		highlight: color.New(color.FgYellow),
+		label:     color.New(color.FgMagenta),
+		success:   color.New(color.FgGreen),
+		danger:    color.New(color.FgRed),
+	},
+	"light": {
+		accent:    color.New(color.FgBlue),
+		highlight: color.New(color.FgRed, color.Bold),
+		label:     color.New(color.FgMagenta),
+		success:   color.New(color.FgGreen, color.Bold),
+		danger:    color.New(color.FgRed),
+	},
+	"high-contrast": {
+		accent:    color.New(color.FgHiWhite, color.Bold, color.Underline),
+		highlight: color.New(color.FgHiYellow, color.Bold),
+		label:     color.New(color.FgHiCyan, color.Bold),
+		success:   color.New(color.FgHiGreen, color.Bold),
+		danger:    color.New(color.FgHiRed, color.Bold),
+	},
+	"no-color": {
+		accent:    color.New(),
+		highlight: color.New(),
+		label:     color.New(),
+		success:   color.New(),
+		danger:    color.New(),
+	},
+}
+
+// theme is the active palette, set by applyTheme.
+var theme = themes["dark"]
+
+// applyTheme activates the named theme, falling back to dark for unknown
+// names. NO_COLOR always wins.
+func applyTheme(name string) {
+	if os.Getenv("NO_COLOR") != "" {
+		name = "no-color"
+	}
+	t, ok := themes[name]
+	if !ok {
+		t = themes["dark"]
+	}
+	theme = t
+	if name == "no-color" {
+		color.NoColor = true
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+}
+
+// say prints a line in c, adding the trailing newline like color.Yellow.
+func say(c *color.Color, format string, a ...interface{}) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	c.Printf(format, a...)
+}
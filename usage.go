@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// ------------------ USAGE LOG ------------------
+
+// Every use of a snippet (copy, get, run, TUI copy, editor insert, keep in
+// review) and every daemon capture is appended to the "usage" bucket, so stats can look at a period
+// instead of lifetime totals.
+//
+// DB schema (usage bucket): unix-nanos|seq (16 bytes, big-endian, so keys
+// sort by time) -> kind|vault|id
+//
+// Events older than usageKeep are dropped as new ones are logged.
+
+const (
+	eventCopy    = "copy"
+	eventGet     = "get"
+	eventRun     = "run"
+	eventInsert  = "insert"
+	eventCapture = "capture"
+	eventKeep    = "keep" // kept in 'grb review'
+)
+
+// usageKeep is how long the usage log keeps events.
+const usageKeep = 2 * 365 * 24 * time.Hour
+
+// logEvent records one event in tx.
+func logEvent(tx *bbolt.Tx, kind, vault, id string) error {
+	b, err := tx.CreateBucketIfNotExists([]byte("usage"))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := pruneEvents(b, now.Add(-usageKeep)); err != nil {
+		return err
+	}
+	seq, _ := b.NextSequence()
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(now.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return b.Put(key, joinRecord(kind, vault, id))
+}
+
+// pruneEvents deletes the events logged before cutoff.
+func pruneEvents(b *bbolt.Bucket, cutoff time.Time) error {
+	end := make([]byte, 8)
+	binary.BigEndian.PutUint64(end, uint64(cutoff.UnixNano()))
+	var old [][]byte
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+		old = append(old, k)
+	}
+	for _, k := range old {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveEvents points the events logged for vault/id at the snippet's new
+// place, so a move keeps its history.
+func moveEvents(tx *bbolt.Tx, vault, id, toVault, toID string) error {
+	b := tx.Bucket([]byte("usage"))
+	if b == nil {
+		return nil
+	}
+	moved := map[string][]byte{}
+	b.ForEach(func(k, v []byte) error {
+		if f := splitRecord(v); len(f) == 3 && f[1] == vault && f[2] == id {
+			moved[string(k)] = joinRecord(f[0], toVault, toID)
+		}
+		return nil
+	})
+	for k, v := range moved {
+		if err := b.Put([]byte(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// logCopies records TUI copies, which don't otherwise write to the DB.
+func logCopies(refs []ref) {
+	db.Update(func(tx *bbolt.Tx) error {
+		for _, r := range refs {
+			if err := logEvent(tx, eventCopy, r.vault, r.id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type usageEvent struct {
+	at    time.Time
+	kind  string
+	vault string
+	id    string
+}
+
+// eventsSince returns the logged events from since on, oldest first.
+func eventsSince(tx *bbolt.Tx, since time.Time) []usageEvent {
+	b := tx.Bucket([]byte("usage"))
+	if b == nil {
+		return nil
+	}
+	start := make([]byte, 8)
+	binary.BigEndian.PutUint64(start, uint64(since.UnixNano()))
+	var events []usageEvent
+	c := b.Cursor()
+	for k, v := c.Seek(start); k != nil; k, v = c.Next() {
+		if len(k) < 8 {
+			continue
+		}
+		fields := splitRecord(v)
+		for len(fields) < 3 {
+			fields = append(fields, "")
+		}
+		at := time.Unix(0, int64(binary.BigEndian.Uint64(k)))
+		events = append(events, usageEvent{at, fields[0], fields[1], fields[2]})
+	}
+	return events
+}
+
+// parseSince reads periods like 30d, 2w or anything time.ParseDuration
+// accepts.
+func parseSince(v string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(v, suffix); ok {
+			days, err := strconv.Atoi(n)
+			if err != nil || days <= 0 {
+				break
+			}
+			return time.Duration(days) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, usagef("%q is not a period like 30d, 2w or 12h", v)
+	}
+	return d, nil
+}
+
+// sparkline draws counts as one block character each.
+func sparkline(counts []int) string {
+	blocks := []rune("▁▂▃▄▅▆▇█")
+	max := 0
+	for _, c := range counts {
+		if c > max {
+			max = c
+		}
+	}
+	var sb strings.Builder
+	for _, c := range counts {
+		switch {
+		case c == 0:
+			sb.WriteRune(' ')
+		case max == 0:
+			sb.WriteRune(blocks[0])
+		default:
+			sb.WriteRune(blocks[(c*(len(blocks)-1)+max-1)/max])
+		}
+	}
+	return sb.String()
+}
+
+// usageSummary tallies one vault's events over a period.
+type usageSummary struct {
+	start    time.Time     // start of the first bucket
+	step     time.Duration // a day, or a week for long periods
+	buckets  []int         // uses per step
+	uses     int
+	captures int
+	counts   map[string]int // uses per snippet id
+}
+
+// summarizeUsage tallies the events of vault over the last since.
+func summarizeUsage(tx *bbolt.Tx, vault string, since time.Duration) usageSummary {
+	now := time.Now()
+	from := now.Add(-since)
+	u := usageSummary{step: 24 * time.Hour, counts: map[string]int{}}
+	if since > 60*24*time.Hour {
+		u.step = 7 * 24 * time.Hour
+	}
+	u.start = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, now.Location())
+	u.buckets = make([]int, int(now.Sub(u.start)/u.step)+1)
+
+	for _, e := range eventsSince(tx, from) {
+		if e.vault != vault {
+			continue
+		}
+		if e.kind == eventCapture {
+			u.captures++
+			continue
+		}
+		u.counts[e.id]++
+		u.uses++
+		if i := int(e.at.Sub(u.start) / u.step); i >= 0 && i < len(u.buckets) {
+			u.buckets[i]++
+		}
+	}
+	return u
+}
+
+// top returns the ids of the n most used snippets, most used first.
+func (u usageSummary) top(n int) []string {
+	ids := make([]string, 0, len(u.counts))
+	for id := range u.counts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if u.counts[ids[i]] != u.counts[ids[j]] {
+			return u.counts[ids[i]] > u.counts[ids[j]]
+		}
+		return lessID(ids[i], ids[j])
+	})
+	if len(ids) > n {
+		ids = ids[:n]
+	}
+	return ids
+}
+
+// unusedSnippet is a snippet with the last time it was used.
+type unusedSnippet struct {
+	snippet
+	lastUsed time.Time
+}
+
+// unusedSince returns vault's unarchived snippets last used before cutoff,
+// oldest first. A snippet's last use is its newest event in the usage log;
+// without one it is the earlier of its created time, which copy and pin
+// move forward, and its vault clock, which legacy snippets got when clocks
+// were added.
+func unusedSince(tx *bbolt.Tx, vault string, cutoff time.Time) []unusedSnippet {
+	last := map[string]time.Time{}
+	for _, e := range eventsSince(tx, time.Unix(0, 0)) {
+		if e.vault == vault {
+			last[e.id] = e.at
+		}
+	}
+	var unused []unusedSnippet
+	tx.Bucket(snippetsKey(vault)).ForEach(func(k, v []byte) error {
+		s := parseSnippet(k, v)
+		if s.archived {
+			return nil
+		}
+		at, ok := last[s.id]
+		if !ok {
+			at = time.Unix(s.created, 0)
+			if c, ok := s.clocks["vault"]; ok && c.at < at.UnixNano() {
+				at = time.Unix(0, c.at)
+			}
+		}
+		if at.Before(cutoff) {
+			unused = append(unused, unusedSnippet{s, at})
+		}
+		return nil
+	})
+	sort.Slice(unused, func(i, j int) bool { return unused[i].lastUsed.Before(unused[j].lastUsed) })
+	return unused
+}
+
+// printUsage shows the period part of 'grb stats': top snippets, a
+// sparkline of uses, captures and snippets unused for unusedFor.
+func printUsage(since time.Duration, top int, unusedFor time.Duration) error {
+	now := time.Now()
+	var u usageSummary
+	var rows []snippet
+	var unused []unusedSnippet
+
+	err := db.View(func(tx *bbolt.Tx) error {
+		u = summarizeUsage(tx, vault, since)
+		b := tx.Bucket(snippetsKey(vault))
+		for _, id := range u.top(top) {
+			s := snippet{id: id, text: theme.danger.Sprint("(deleted)")}
+			if v := b.Get([]byte(id)); v != nil {
+				s = parseSnippet([]byte(id), v)
+				s.text = s.shownText()
+			}
+			rows = append(rows, s)
+		}
+		unused = unusedSince(tx, vault, now.Add(-unusedFor))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	unit := "day"
+	if u.step > 24*time.Hour {
+		unit = "week"
+	}
+
+	accent := theme.accent.SprintFunc()
+	success := theme.success.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+	label := theme.label.SprintFunc()
+
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Println(accent(fmt.Sprintf("📈 Last %s", formatPeriod(since))))
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Printf("%-18s : %s\n", "Uses", success(strconv.Itoa(u.uses)))
+	fmt.Printf("%-18s : %s\n", "Daemon captures", success(strconv.Itoa(u.captures)))
+	fmt.Printf("%-18s : %s  (per %s, %s → today)\n", "Activity",
+		highlight(sparkline(u.buckets)), unit, u.start.Format("Jan 2"))
+
+	if len(rows) > 0 {
+		fmt.Println()
+		fmt.Println(accent(fmt.Sprintf("Top %d", len(rows))))
+		table := make([][]string, len(rows))
+		for i, s := range rows {
+			text := fmt.Sprintf("%s %s", s.text, success(fmt.Sprintf("(🔥 %d)", u.counts[s.id])))
+			table[i] = []string{accent(s.id), text, label(orDash(s.tag)), highlight(orDash(s.alias))}
+		}
+		printSnippetTable(table)
+	}
+
+	fmt.Println()
+	fmt.Printf("%s (%d)\n", accent(fmt.Sprintf("💤 Unused for %s", formatPeriod(unusedFor))), len(unused))
+	for i, s := range unused {
+		if i == 10 {
+			fmt.Printf("   … and %d more\n", len(unused)-10)
+			break
+		}
+		age := int(now.Sub(s.lastUsed).Hours() / 24)
+		fmt.Printf("   [%s] %s %s\n", accent(s.id), oneLine(truncate(s.shownText(), 50)), label(fmt.Sprintf("(%dd)", age)))
+	}
+	return nil
+}
+
+// formatPeriod prints whole days as "30 days" and anything else as a
+// duration.
+func formatPeriod(d time.Duration) string {
+	if d%(24*time.Hour) != 0 {
+		return d.String()
+	}
+	if days := int(d / (24 * time.Hour)); days != 1 {
+		return fmt.Sprintf("%d days", days)
+	}
+	return "1 day"
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
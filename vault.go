@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go.etcd.io/bbolt"
+)
+
+// ------------------ VAULTS ------------------
+
+// A vault is a separate snippets bucket with its own ids and aliases in the
+// same DB file. The default vault keeps the original "snippets" and
+// "aliases" buckets; vault <name> uses "snippets.<name>" and
+// "aliases.<name>", so moving a snippet is a single transaction.
+
+const defaultVault = "default"
+
+var (
+	vault     = defaultVault // vault used by commands: --vault, GRB_VAULT or 'grb vault use'
+	allVaults bool           // search/TUI read every vault (--all-vaults)
+)
+
+func snippetsKey(name string) []byte {
+	if name == defaultVault {
+		return []byte("snippets")
+	}
+	return []byte("snippets." + name)
+}
+
+func aliasesKey(name string) []byte {
+	if name == defaultVault {
+		return []byte("aliases")
+	}
+	return []byte("aliases." + name)
+}
+
+// validateVault keeps vault names usable as bucket suffixes and on the
+// command line.
+func validateVault(name string) error {
+	if name == "" {
+		return usagef("vault name must not be empty")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return usagef("vault name %q may only contain letters, digits, '-' and '_'", name)
+		}
+	}
+	return nil
+}
+
+// listVaults returns every vault name, default first.
+func listVaults(tx *bbolt.Tx) []string {
+	var names []string
+	tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+		if n, ok := strings.CutPrefix(string(name), "snippets."); ok {
+			names = append(names, n)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return append([]string{defaultVault}, names...)
+}
+
+func vaultExists(tx *bbolt.Tx, name string) bool {
+	return tx.Bucket(snippetsKey(name)) != nil
+}
+
+// searchVaults lists the vaults read by list-style queries.
+func searchVaults() []string {
+	if !allVaults {
+		return []string{vault}
+	}
+	var names []string
+	db.View(func(tx *bbolt.Tx) error {
+		names = listVaults(tx)
+		return nil
+	})
+	return names
+}
+
+// selectVault sets the vault from, in order, --vault, GRB_VAULT and the
+// one stored by 'grb vault use'.
+func selectVault(flag string) error {
+	explicit := firstNonEmpty(flag, os.Getenv("GRB_VAULT"))
+	return db.View(func(tx *bbolt.Tx) error {
+		name := explicit
+		if name == "" {
+			name = defaultVault
+			if meta := tx.Bucket([]byte("meta")); meta != nil {
+				if used := string(meta.Get([]byte("vault"))); used != "" && vaultExists(tx, used) {
+					name = used
+				}
+			}
+		}
+		if !vaultExists(tx, name) {
+			return fmt.Errorf("vault %w: %s", ErrNotFound, name)
+		}
+		vault = name
+		return nil
+	})
+}
+
+// createVault adds an empty vault.
+func createVault(name string) error {
+	if err := validateVault(name); err != nil {
+		return err
+	}
+	return db.Update(func(tx *bbolt.Tx) error {
+		if vaultExists(tx, name) {
+			return fmt.Errorf("vault %q already exists", name)
+		}
+		if _, err := tx.CreateBucket(snippetsKey(name)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(aliasesKey(name))
+		return err
+	})
+}
+
+// useVault makes name the vault used without --vault.
+func useVault(name string) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		if !vaultExists(tx, name) {
+			return fmt.Errorf("vault %w: %s", ErrNotFound, name)
+		}
+		meta, err := tx.CreateBucketIfNotExists([]byte("meta"))
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte("vault"), []byte(name))
+	})
+}
+
+// removeVault deletes a vault. Non-empty vaults need force.
+func removeVault(name string, force bool) (int, error) {
+	if name == defaultVault {
+		return 0, usagef("the default vault can't be removed")
+	}
+	count := 0
+	err := db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(snippetsKey(name))
+		if b == nil {
+			return fmt.Errorf("vault %w: %s", ErrNotFound, name)
+		}
+		count = b.Stats().KeyN
+		if count > 0 && !force {
+			return usagef("vault %q has %d snippet(s); use --force to remove it", name, count)
+		}
+		err := b.ForEach(func(k, v []byte) error {
+			if s := parseSnippet(k, v); s.uuid != "" {
+				return putTombstone(tx, s.uuid, newStamp())
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(snippetsKey(name)); err != nil {
+			return err
+		}
+		if tx.Bucket(aliasesKey(name)) != nil {
+			if err := tx.DeleteBucket(aliasesKey(name)); err != nil {
+				return err
+			}
+		}
+		if meta := tx.Bucket([]byte("meta")); meta != nil && string(meta.Get([]byte("vault"))) == name {
+			return meta.Delete([]byte("vault"))
+		}
+		return nil
+	})
+	return count, err
+}
+
+// moveSnippet moves a snippet from the current vault to another one, where
+// it gets a new id. Its alias moves with it and must be free there.
+func moveSnippet(idOrAlias, to string) (snippet, error) {
+	var s snippet
+	err := db.Update(func(tx *bbolt.Tx) error {
+		if !vaultExists(tx, to) {
+			return fmt.Errorf("vault %w: %s", ErrNotFound, to)
+		}
+		if to == vault {
+			return usagef("snippet is already in vault %q", to)
+		}
+		var ok bool
+		if s, ok = findSnippet(tx, vault, idOrAlias); !ok {
+			return notFound(idOrAlias)
+		}
+		if err := removeSnippet(tx, vault, s); err != nil {
+			return err
+		}
+
+		dst := tx.Bucket(snippetsKey(to))
+		id, _ := dst.NextSequence()
+		if err := moveEvents(tx, vault, s.id, to, fmt.Sprintf("%d", id)); err != nil {
+			return err
+		}
+		s.id = fmt.Sprintf("%d", id)
+		s.vault = to
+		if s.clocks == nil {
+			s.clocks = map[string]stamp{}
+		}
+		s.clocks["vault"] = newStamp()
+		if err := claimAlias(tx, to, s.alias, s.id); err != nil {
+			return err
+		}
+		return putSnippet(dst, s.id, s)
+	})
+	return s, err
+}
+
+// printVaults lists every vault with its size, marking the current one.
+func printVaults() error {
+	type row struct {
+		name  string
+		count int
+	}
+	var rows []row
+	err := db.View(func(tx *bbolt.Tx) error {
+		for _, name := range listVaults(tx) {
+			rows = append(rows, row{name, tx.Bucket(snippetsKey(name)).Stats().KeyN})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	accent := theme.accent.SprintFunc()
+	highlight := theme.highlight.SprintFunc()
+
+	fmt.Println("─────────────────────────────────────────────")
+	fmt.Printf("%s (total: %d)\n", accent("🗄 Vaults"), len(rows))
+	fmt.Println("─────────────────────────────────────────────")
+	for _, r := range rows {
+		mark := "  "
+		name := r.name
+		if r.name == vault {
+			mark = "▶ "
+			name = highlight(r.name)
+		}
+		fmt.Printf("%s%s  (%d snippets)\n", mark, padRight(name, 20), r.count)
+	}
+	fmt.Println("💡 Tip: Use 'grb vault use <name>' or --vault to switch")
+	return nil
+}
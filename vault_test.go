@@ -0,0 +1,43 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+func TestMoveKeepsUsage(t *testing.T) {
+	useDB(t, filepath.Join(t.TempDir(), "grb.db"))
+	id, err := createSnippet("echo one", "", "one", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		return logEvent(tx, eventCopy, vault, id)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	editStored(t, "one", func(s *snippet) { s.created = time.Now().AddDate(-1, 0, 0).Unix() })
+	if err := createVault("team"); err != nil {
+		t.Fatal(err)
+	}
+	moved, err := moveSnippet("one", "team")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.View(func(tx *bbolt.Tx) error {
+		events := eventsSince(tx, time.Unix(0, 0))
+		if len(events) != 1 || events[0].vault != "team" || events[0].id != moved.id {
+			t.Errorf("events = %+v, want the copy on team/%s", events, moved.id)
+		}
+		// Created a year ago, but copied a moment ago.
+		if unused := unusedSince(tx, "team", time.Now().Add(-24*time.Hour)); len(unused) != 0 {
+			t.Errorf("unused after the move: %+v", unused)
+		}
+		return nil
+	})
+}